@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"gemini/events"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Client is one websocket connection and the auctions it watches.
+// subs, pending and the sending side of send are only touched by the hub goroutine.
+type Client struct {
+	conn *websocket.Conn
+	send chan Event
+	// binary clients negotiated the protobuf subprotocol and get binary frames
+	binary bool
+	// initial are the auctions from the query string, joined on register
+	initial []string
+	// since is the ?since= resume point for the initial auctions
+	since *uint64
+	subs  map[string]bool
+	// pending holds live events per auction while its snapshot is loading
+	pending map[string][]Event
+}
+
+// enqueue queues an event without blocking the hub.
+// It returns false when the client should be disconnected.
+func (c *Client) enqueue(ev Event, policy SlowConsumerPolicy) bool {
+	select {
+	case c.send <- ev:
+		return true
+	default:
+	}
+
+	switch policy {
+	case DropClient:
+		return false
+	case Coalesce:
+		pending := coalesce(c.drain(), ev)
+		if len(pending) > cap(c.send) {
+			// nothing left to coalesce, dropping any of these would lose a snapshot or lifecycle event
+			return false
+		}
+		c.refill(pending)
+	default:
+		pending := append(c.drain(), ev)
+		if len(pending) > cap(c.send) {
+			// a skipped bid is superseded by the next one, a lost snapshot or lifecycle event is not,
+			// so then the client is dropped and resumes with ?since=
+			if pending[0].Type != events.TypeBidPlaced {
+				return false
+			}
+			pending = pending[1:]
+		}
+		c.refill(pending)
+	}
+	return true
+}
+
+// drain empties the queue and returns what was in it
+func (c *Client) drain() []Event {
+	var queued []Event
+	for {
+		select {
+		case ev := <-c.send:
+			queued = append(queued, ev)
+		default:
+			return queued
+		}
+	}
+}
+
+// coalesce appends ev to the queued events, keeping only the newest bid per auction.
+// A newer bid supersedes an older one, everything else (snapshots, lifecycle events) is kept in order.
+func coalesce(queued []Event, ev Event) []Event {
+	var out []Event
+	for _, q := range append(queued, ev) {
+		if q.Type == events.TypeBidPlaced {
+			for i, prev := range out {
+				if prev.Type == events.TypeBidPlaced && prev.Auction == q.Auction {
+					out = append(out[:i], out[i+1:]...)
+					break
+				}
+			}
+		}
+		out = append(out, q)
+	}
+	return out
+}
+
+// refill queues events taken out by drain. Only the hub sends on the queue,
+// so they never block as long as there are no more than its capacity.
+func (c *Client) refill(pending []Event) {
+	for _, ev := range pending {
+		c.send <- ev
+	}
+}
+
+// writePump is the only goroutine writing to the connection, it also sends the heartbeat pings
+func (c *Client) writePump(cfg Config) {
+	ticker := time.NewTicker(cfg.PingInterval)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+	for {
+		select {
+		case ev, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout))
+			if !ok {
+				// the hub closed the queue, say goodbye properly
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			frame, data := websocket.TextMessage, ev.Data
+			if c.binary {
+				frame, data = websocket.BinaryMessage, ev.Proto
+			}
+			if err := c.conn.WriteMessage(frame, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// protobufSubprotocol is the websocket subprotocol for binary protobuf frames, everyone else gets JSON text frames
+const protobufSubprotocol = "protobuf"
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		return true // allow all origins (dev only)
+	},
+	Subprotocols: []string{protobufSubprotocol},
+}
+
+// clientMessage is what clients send to change their subscriptions, e.g.
+// {"action": "subscribe", "auction": "5", "since": 41}
+type clientMessage struct {
+	Action  string  `json:"action"`
+	Auction string  `json:"auction"`
+	Since   *uint64 `json:"since"`
+}
+
+// auctionsFromQuery reads ?auction=1&auction=2 and ?auction=1,2
+func auctionsFromQuery(r *http.Request) []string {
+	var ids []string
+	for _, v := range r.URL.Query()["auction"] {
+		for _, id := range strings.Split(v, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// sinceFromQuery reads ?since=<seq>, nil when absent or invalid
+func sinceFromQuery(r *http.Request) *uint64 {
+	v := r.URL.Query().Get("since")
+	if v == "" {
+		return nil
+	}
+	seq, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &seq
+}
+
+func wsHandler(hub *Hub, cfg Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Println("Upgrade error:", err)
+			return
+		}
+
+		client := &Client{
+			conn:    conn,
+			send:    make(chan Event, cfg.SendBuffer),
+			binary:  conn.Subprotocol() == protobufSubprotocol,
+			initial: auctionsFromQuery(r),
+			since:   sinceFromQuery(r),
+			subs:    make(map[string]bool),
+			pending: make(map[string][]Event),
+		}
+		hub.register <- client
+		go client.writePump(cfg)
+
+		// a connection that stops answering pings hits the read deadline and gets dropped
+		conn.SetReadLimit(cfg.MaxMessageSize)
+		conn.SetReadDeadline(time.Now().Add(cfg.PongTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(cfg.PongTimeout))
+		})
+
+		// Reader goroutine handles subscribe/unsubscribe and detects disconnect.
+		// Close frames from the client are answered by gorilla's default close handler.
+		go func() {
+			defer func() {
+				hub.unregister <- client
+			}()
+
+			for {
+				_, data, err := conn.ReadMessage()
+				if err != nil {
+					break
+				}
+				var msg clientMessage
+				if err := json.Unmarshal(data, &msg); err != nil || msg.Auction == "" {
+					continue
+				}
+				switch msg.Action {
+				case "subscribe":
+					hub.subscribe <- subscription{client: client, auction: msg.Auction, subscribe: true, since: msg.Since}
+				case "unsubscribe":
+					hub.subscribe <- subscription{client: client, auction: msg.Auction, subscribe: false}
+				}
+			}
+		}()
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"gemini/events"
+	"testing"
+)
+
+func TestCoalesceKeepsLifecycleEvents(t *testing.T) {
+	c := &Client{send: make(chan Event, 3)}
+	c.send <- Event{Auction: "1", Type: TypeSnapshot, Seq: 4}
+	c.send <- Event{Auction: "1", Type: events.TypeBidPlaced, Seq: 5}
+	c.send <- Event{Auction: "1", Type: events.TypeAuctionExtended, Seq: 6}
+
+	if !c.enqueue(Event{Auction: "1", Type: events.TypeBidPlaced, Seq: 7}, Coalesce) {
+		t.Fatal("client dropped although a bid could be coalesced")
+	}
+
+	var got []uint64
+	for _, ev := range c.drain() {
+		got = append(got, ev.Seq)
+	}
+	want := []uint64{4, 6, 7}
+	if len(got) != len(want) {
+		t.Fatalf("queue = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("queue = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestDropOldestOnlyDropsBids(t *testing.T) {
+	c := &Client{send: make(chan Event, 3)}
+	c.send <- Event{Auction: "1", Type: events.TypeBidPlaced, Seq: 4}
+	c.send <- Event{Auction: "1", Type: TypeSnapshot, Seq: 5}
+	c.send <- Event{Auction: "1", Type: events.TypeBidPlaced, Seq: 6}
+
+	if !c.enqueue(Event{Auction: "1", Type: events.TypeBidPlaced, Seq: 7}, DropOldest) {
+		t.Fatal("client dropped although the oldest queued event was a bid")
+	}
+	got := c.drain()
+	want := []uint64{5, 6, 7}
+	if len(got) != len(want) {
+		t.Fatalf("queue = %v, want seqs %v", got, want)
+	}
+	for i, ev := range got {
+		if ev.Seq != want[i] {
+			t.Fatalf("queue = %v, want seqs %v", got, want)
+		}
+		c.send <- ev
+	}
+
+	// the snapshot is now the oldest, it must not be dropped to make room
+	if c.enqueue(Event{Auction: "1", Type: events.TypeBidPlaced, Seq: 8}, DropOldest) {
+		t.Fatal("client kept although a snapshot had to be dropped")
+	}
+}
+
+func TestCoalesceDropsClientWithoutBidsToMerge(t *testing.T) {
+	c := &Client{send: make(chan Event, 2)}
+	c.send <- Event{Auction: "1", Type: TypeSnapshot, Seq: 4}
+	c.send <- Event{Auction: "1", Type: events.TypeAuctionStarted, Seq: 5}
+
+	if c.enqueue(Event{Auction: "1", Type: events.TypeAuctionClosed, Seq: 6}, Coalesce) {
+		t.Fatal("client kept although a lifecycle event had to be dropped")
+	}
+}
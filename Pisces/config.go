@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// SlowConsumerPolicy decides what happens when a client's send queue is full
+type SlowConsumerPolicy string
+
+const (
+	// DropOldest discards the oldest queued message to make room if it is a bid.
+	// When it is a snapshot or lifecycle event the client is dropped instead, to resume with ?since=.
+	DropOldest SlowConsumerPolicy = "drop-oldest"
+	// DropClient disconnects the client
+	DropClient SlowConsumerPolicy = "drop-client"
+	// Coalesce keeps only the latest queued bid per auction, i.e. the latest price.
+	// Snapshots and lifecycle events are never coalesced, a client whose queue is full of them is dropped.
+	Coalesce SlowConsumerPolicy = "coalesce"
+)
+
+type Config struct {
+	SendBuffer   int
+	WriteTimeout time.Duration
+	SlowConsumer SlowConsumerPolicy
+	// PongTimeout is how long a connection may stay silent before it is considered dead
+	PongTimeout time.Duration
+	// PingInterval must be shorter than PongTimeout
+	PingInterval time.Duration
+	// MaxMessageSize limits frames sent by clients, in bytes
+	MaxMessageSize int64
+	// DBDSN points at the tauras database used for snapshots, empty disables them
+	DBDSN string
+	// SnapshotBids is how many recent bids a snapshot carries
+	SnapshotBids int
+	// ReplayBuffer is how many recent events per auction are kept for resuming clients
+	ReplayBuffer int
+	// KafkaBrokers is the bootstrap server list
+	KafkaBrokers string
+	// InstanceID identifies this replica, it is part of the consumer group id
+	InstanceID string
+	// GroupID is the kafka consumer group, unique per instance so every replica sees every event
+	GroupID string
+}
+
+func LoadConfig() Config {
+	cfg := Config{
+		SendBuffer:     intFromEnv("PISCES_SEND_BUFFER", 64),
+		WriteTimeout:   durationFromEnv("PISCES_WRITE_TIMEOUT", 10*time.Second),
+		SlowConsumer:   SlowConsumerPolicy(getEnv("PISCES_SLOW_CONSUMER", string(DropOldest))),
+		PongTimeout:    durationFromEnv("PISCES_PONG_TIMEOUT", 60*time.Second),
+		PingInterval:   durationFromEnv("PISCES_PING_INTERVAL", 50*time.Second),
+		MaxMessageSize: int64(intFromEnv("PISCES_MAX_MESSAGE_SIZE", 4096)),
+		DBDSN:          os.Getenv("DB_DSN"),
+		SnapshotBids:   intFromEnv("PISCES_SNAPSHOT_BIDS", 20),
+		ReplayBuffer:   intFromEnv("PISCES_REPLAY_BUFFER", 256),
+		KafkaBrokers:   getEnv("KAFKA_BROKERS", "kafka:9092"),
+		InstanceID:     getEnv("PISCES_INSTANCE_ID", defaultInstanceID()),
+	}
+	cfg.GroupID = getEnv("PISCES_GROUP_ID", "pisces-"+cfg.InstanceID)
+	if cfg.PingInterval >= cfg.PongTimeout {
+		cfg.PingInterval = cfg.PongTimeout * 9 / 10
+		log.Printf("PISCES_PING_INTERVAL must be below PISCES_PONG_TIMEOUT, using %s", cfg.PingInterval)
+	}
+	switch cfg.SlowConsumer {
+	case DropOldest, DropClient, Coalesce:
+	default:
+		log.Printf("unknown PISCES_SLOW_CONSUMER %q, using %s", cfg.SlowConsumer, DropOldest)
+		cfg.SlowConsumer = DropOldest
+	}
+	return cfg
+}
+
+// defaultInstanceID is the hostname (the container id under docker) plus a random suffix,
+// so restarts and replicas on the same host never share a consumer group
+func defaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "pisces"
+	}
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return host + "-" + hex.EncodeToString(suffix)
+}
+
+func getEnv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func intFromEnv(key string, def int) int {
+	v, err := strconv.Atoi(getEnv(key, strconv.Itoa(def)))
+	if err != nil || v <= 0 {
+		log.Printf("invalid %s, using %d", key, def)
+		return def
+	}
+	return v
+}
+
+func durationFromEnv(key string, def time.Duration) time.Duration {
+	v, err := time.ParseDuration(getEnv(key, def.String()))
+	if err != nil || v <= 0 {
+		log.Printf("invalid %s, using %s", key, def)
+		return def
+	}
+	return v
+}
@@ -3,6 +3,15 @@ module pisces
 go 1.25.4
 
 require (
-	github.com/confluentinc/confluent-kafka-go v1.9.2 // indirect
-	github.com/gorilla/websocket v1.5.3 // indirect
+	github.com/confluentinc/confluent-kafka-go v1.9.2
+	github.com/go-sql-driver/mysql v1.9.3
+	github.com/gorilla/websocket v1.5.3
 )
+
+require (
+	filippo.io/edwards25519 v1.1.0 // indirect
+	gemini v0.0.0
+	google.golang.org/protobuf v1.36.11 // indirect
+)
+
+replace gemini => ../gemini
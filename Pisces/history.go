@@ -0,0 +1,61 @@
+package main
+
+import "time"
+
+// history is a bounded ring of the most recent events of one auction, used to resume clients
+type history struct {
+	events   []Event
+	start    int
+	size     int
+	lastUsed time.Time
+}
+
+func newHistory(capacity int) *history {
+	return &history{events: make([]Event, capacity)}
+}
+
+func (r *history) at(i int) Event {
+	return r.events[(r.start+i)%len(r.events)]
+}
+
+func (r *history) add(ev Event) {
+	r.lastUsed = time.Now()
+	if r.size < len(r.events) {
+		r.events[(r.start+r.size)%len(r.events)] = ev
+		r.size++
+		return
+	}
+	r.events[r.start] = ev
+	r.start = (r.start + 1) % len(r.events)
+}
+
+func (r *history) has(seq uint64) bool {
+	for i := 0; i < r.size; i++ {
+		if r.at(i).Seq == seq {
+			return true
+		}
+	}
+	return false
+}
+
+// since returns the events after seq in order.
+// ok is false when the ring no longer reaches back to seq and the client needs a snapshot instead.
+func (r *history) since(seq uint64) (events []Event, ok bool) {
+	if r.size == 0 {
+		return nil, false
+	}
+	if oldest := r.at(0).Seq; seq+1 < oldest {
+		return nil, false
+	}
+	if newest := r.at(r.size - 1).Seq; seq > newest {
+		// the client knows more than we do, e.g. after a restart
+		return nil, false
+	}
+	r.lastUsed = time.Now()
+	for i := 0; i < r.size; i++ {
+		if ev := r.at(i); ev.Seq > seq {
+			events = append(events, ev)
+		}
+	}
+	return events, true
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"expvar"
+	"log"
+	"time"
+)
+
+// connections is exported on /debug/vars
+var connections = expvar.NewInt("pisces_connections")
+
+// Event is a kafka message together with the auction it belongs to
+type Event struct {
+	Auction string
+	// Type is the envelope type, e.g. events.TypeBidPlaced
+	Type string
+	// Seq orders events within an auction, 0 when unknown
+	Seq uint64
+	// Data is the JSON envelope, Proto the same envelope for clients on the protobuf subprotocol
+	Data  []byte
+	Proto []byte
+}
+
+// historyIdleTimeout is how long the replay buffer of an auction nobody watches is kept
+const historyIdleTimeout = time.Hour
+
+// maxPendingEvents bounds how many live events are held back per subscription while its snapshot loads
+const maxPendingEvents = 1024
+
+type snapshotResult struct {
+	client  *Client
+	auction string
+	event   Event
+	err     error
+}
+
+type subscription struct {
+	client    *Client
+	auction   string
+	subscribe bool
+	// since is the last seq the client saw, nil for a fresh subscription
+	since *uint64
+}
+
+// Hub keeps a room of clients per auction and routes every event only to that room.
+// Fan-out never blocks: each client has its own queue and writer goroutine.
+type Hub struct {
+	policy       SlowConsumerPolicy
+	snapshots    SnapshotSource
+	clients      map[*Client]bool
+	rooms        map[string]map[*Client]bool
+	histories    map[string]*history
+	historySize  int
+	broadcast    chan Event
+	register     chan *Client
+	unregister   chan *Client
+	subscribe    chan subscription
+	shutdown     chan chan struct{}
+	snapshotDone chan snapshotResult
+}
+
+// NewHub creates a hub, snapshots may be nil to skip the initial snapshot.
+// historySize is how many recent events per auction are kept for clients resuming with ?since=.
+func NewHub(policy SlowConsumerPolicy, snapshots SnapshotSource, historySize int) *Hub {
+	return &Hub{
+		policy:       policy,
+		snapshots:    snapshots,
+		clients:      make(map[*Client]bool),
+		rooms:        make(map[string]map[*Client]bool),
+		histories:    make(map[string]*history),
+		historySize:  historySize,
+		broadcast:    make(chan Event, 256),
+		register:     make(chan *Client),
+		unregister:   make(chan *Client),
+		subscribe:    make(chan subscription),
+		shutdown:     make(chan chan struct{}),
+		snapshotDone: make(chan snapshotResult),
+	}
+}
+
+func (h *Hub) Run() {
+	evict := time.NewTicker(time.Minute)
+	defer evict.Stop()
+	for {
+		select {
+
+		case client := <-h.register:
+			h.add(client)
+			log.Println("Client connected. Total:", len(h.clients))
+
+		case client := <-h.unregister:
+			if _, ok := h.clients[client]; ok {
+				h.remove(client)
+				log.Println("Client disconnected. Total:", len(h.clients))
+			}
+
+		case sub := <-h.subscribe:
+			if _, ok := h.clients[sub.client]; !ok {
+				break
+			}
+			if sub.subscribe {
+				h.join(sub.client, sub.auction, sub.since)
+			} else {
+				h.leave(sub.client, sub.auction)
+			}
+
+		case ev := <-h.broadcast:
+			h.fanOut(ev)
+
+		case res := <-h.snapshotDone:
+			h.finishSnapshot(res)
+
+		case <-evict.C:
+			for auction, hist := range h.histories {
+				if _, watched := h.rooms[auction]; !watched && time.Since(hist.lastUsed) > historyIdleTimeout {
+					delete(h.histories, auction)
+				}
+			}
+
+		case done := <-h.shutdown:
+			for client := range h.clients {
+				h.remove(client)
+			}
+			close(done)
+			return
+		}
+	}
+}
+
+// Shutdown closes every client with a close frame and stops the hub
+func (h *Hub) Shutdown() {
+	done := make(chan struct{})
+	h.shutdown <- done
+	<-done
+}
+
+// add registers a client and joins the auctions from its query string
+func (h *Hub) add(client *Client) {
+	h.clients[client] = true
+	connections.Set(int64(len(h.clients)))
+	for _, auction := range client.initial {
+		if !h.clients[client] {
+			// dropped while joining an earlier auction, joining more would put it back in a room
+			return
+		}
+		h.join(client, auction, client.since)
+	}
+}
+
+// fanOut sends an event to every client in its auction's room
+func (h *Hub) fanOut(ev Event) {
+	if !h.record(ev) {
+		// already broadcast, the outbox delivers at least once
+		return
+	}
+	for client := range h.rooms[ev.Auction] {
+		if pending, ok := client.pending[ev.Auction]; ok {
+			// snapshot still loading, hold the event back
+			if len(pending) >= maxPendingEvents {
+				log.Println("Dropping client, too many events while loading snapshot")
+				h.remove(client)
+				continue
+			}
+			client.pending[ev.Auction] = append(pending, ev)
+			continue
+		}
+		h.deliver(client, ev)
+	}
+}
+
+func (h *Hub) deliver(client *Client, ev Event) {
+	if !client.enqueue(ev, h.policy) {
+		log.Println("Dropping slow client")
+		h.remove(client)
+	}
+}
+
+// record adds the event to the auction's replay buffer, returning false for duplicates
+func (h *Hub) record(ev Event) bool {
+	if ev.Seq == 0 {
+		return true
+	}
+	hist, ok := h.histories[ev.Auction]
+	if !ok {
+		hist = newHistory(h.historySize)
+		h.histories[ev.Auction] = hist
+	}
+	if hist.has(ev.Seq) {
+		return false
+	}
+	hist.add(ev)
+	return true
+}
+
+// join subscribes the client to an auction. A client resuming from since gets the missed events
+// replayed from the buffer, anyone else gets a snapshot. So does a gap the buffer no longer covers,
+// or one too large for the client's free queue space, which could only be replayed by dropping events.
+func (h *Hub) join(client *Client, auction string, since *uint64) {
+	if client.subs[auction] {
+		return
+	}
+	room, ok := h.rooms[auction]
+	if !ok {
+		room = make(map[*Client]bool)
+		h.rooms[auction] = room
+	}
+	room[client] = true
+	client.subs[auction] = true
+
+	if since != nil {
+		if hist, ok := h.histories[auction]; ok {
+			if missed, ok := hist.since(*since); ok && len(missed) <= cap(client.send)-len(client.send) {
+				for _, ev := range missed {
+					if !h.clients[client] {
+						return
+					}
+					h.deliver(client, ev)
+				}
+				return
+			}
+		}
+	}
+
+	if h.snapshots != nil {
+		client.pending[auction] = nil
+		go h.loadSnapshot(client, auction)
+	}
+}
+
+// loadSnapshot runs outside the hub goroutine so a slow query never stalls fan-out
+func (h *Hub) loadSnapshot(client *Client, auction string) {
+	res := snapshotResult{client: client, auction: auction}
+	env, err := h.snapshots.Snapshot(auction)
+	if err == nil {
+		res.event, err = newEvent(env)
+	}
+	res.err = err
+	h.snapshotDone <- res
+}
+
+// finishSnapshot sends the snapshot followed by the events that arrived while it was loading,
+// skipping those the snapshot already covers, so the client sees a gap-free stream
+func (h *Hub) finishSnapshot(res snapshotResult) {
+	client := res.client
+	pending, ok := client.pending[res.auction]
+	if !ok || !h.clients[client] {
+		return
+	}
+	delete(client.pending, res.auction)
+
+	if res.err != nil {
+		log.Printf("Snapshot for auction %s failed: %v", res.auction, res.err)
+	} else {
+		h.deliver(client, res.event)
+	}
+	for _, ev := range pending {
+		if !h.clients[client] {
+			return
+		}
+		if res.err == nil && ev.Seq != 0 && ev.Seq <= res.event.Seq {
+			continue
+		}
+		h.deliver(client, ev)
+	}
+}
+
+func (h *Hub) leave(client *Client, auction string) {
+	delete(client.subs, auction)
+	delete(client.pending, auction)
+	if room, ok := h.rooms[auction]; ok {
+		delete(room, client)
+		if len(room) == 0 {
+			delete(h.rooms, auction)
+		}
+	}
+}
+
+func (h *Hub) remove(client *Client) {
+	for auction := range client.subs {
+		h.leave(client, auction)
+	}
+	delete(h.clients, client)
+	connections.Set(int64(len(h.clients)))
+	// closing the queue makes the writer send a close frame and hang up
+	close(client.send)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"gemini/events"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeSnapshots returns an empty snapshot at a fixed seq
+type fakeSnapshots struct{ seq uint64 }
+
+func (f fakeSnapshots) Snapshot(auction string) (events.Envelope, error) {
+	return events.New(auction, f.seq, Snapshot{Status: "live", EndTime: time.Now().Add(time.Hour), Bids: []SnapshotBid{}})
+}
+
+func newTestClient(queue int, since *uint64, auctions ...string) *Client {
+	return &Client{
+		send:    make(chan Event, queue),
+		initial: auctions,
+		since:   since,
+		subs:    make(map[string]bool),
+		pending: make(map[string][]Event),
+	}
+}
+
+// broadcastBids records seq 1..n for each auction, as if they had been broadcast before the client connected
+func broadcastBids(h *Hub, n int, auctions ...string) {
+	for _, auction := range auctions {
+		for seq := 1; seq <= n; seq++ {
+			h.record(Event{Auction: auction, Type: events.TypeBidPlaced, Seq: uint64(seq), Data: []byte(strconv.Itoa(seq))})
+		}
+	}
+}
+
+func queuedSeqs(c *Client) []uint64 {
+	var seqs []uint64
+	for _, ev := range c.drain() {
+		seqs = append(seqs, ev.Seq)
+	}
+	return seqs
+}
+
+func TestReplayLargerThanQueueFallsBackToSnapshot(t *testing.T) {
+	for _, policy := range []SlowConsumerPolicy{DropClient, DropOldest, Coalesce} {
+		t.Run(string(policy), func(t *testing.T) {
+			h := NewHub(policy, fakeSnapshots{seq: 100}, 256)
+			broadcastBids(h, 100, "1", "2")
+
+			since := uint64(0)
+			client := newTestClient(64, &since, "1", "2")
+			h.add(client)
+
+			if !h.clients[client] {
+				t.Fatal("client was dropped while replaying")
+			}
+			if len(client.send) != 0 {
+				t.Fatalf("%d events replayed, want a snapshot instead", len(client.send))
+			}
+			for range 2 {
+				h.finishSnapshot(<-h.snapshotDone)
+			}
+
+			got := client.drain()
+			if len(got) != 2 {
+				t.Fatalf("got %d events, want one snapshot per auction", len(got))
+			}
+			for _, ev := range got {
+				if ev.Type != TypeSnapshot || ev.Seq != 100 {
+					t.Errorf("got %s seq %d, want a snapshot at seq 100", ev.Type, ev.Seq)
+				}
+			}
+
+			// the client is still in both rooms and keeps receiving live events
+			h.fanOut(Event{Auction: "2", Type: events.TypeBidPlaced, Seq: 101})
+			if seqs := queuedSeqs(client); len(seqs) != 1 || seqs[0] != 101 {
+				t.Errorf("live events = %v, want [101]", seqs)
+			}
+		})
+	}
+}
+
+func TestReplayWithinQueueIsGapFree(t *testing.T) {
+	h := NewHub(DropOldest, fakeSnapshots{seq: 100}, 256)
+	broadcastBids(h, 100, "1")
+
+	since := uint64(90)
+	client := newTestClient(64, &since, "1")
+	h.add(client)
+
+	if _, loading := client.pending["1"]; loading {
+		t.Fatal("snapshot requested although the gap fits in the queue")
+	}
+	seqs := queuedSeqs(client)
+	if len(seqs) != 10 {
+		t.Fatalf("replayed %v, want 91..100", seqs)
+	}
+	for i, seq := range seqs {
+		if seq != uint64(91+i) {
+			t.Fatalf("replayed %v, want 91..100", seqs)
+		}
+	}
+}
@@ -1,110 +1,111 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"gemini/events"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
-	"github.com/gorilla/websocket"
+	_ "github.com/go-sql-driver/mysql"
 )
 
-type Hub struct {
-	clients    map[*websocket.Conn]bool
-	broadcast  chan []byte
-	register   chan *websocket.Conn
-	unregister chan *websocket.Conn
-}
-
-func NewHub() *Hub {
-	return &Hub{
-		clients:    make(map[*websocket.Conn]bool),
-		broadcast:  make(chan []byte),
-		register:   make(chan *websocket.Conn),
-		unregister: make(chan *websocket.Conn),
+// decodeEvent parses a kafka message in the encoding named by its content-type header.
+// Legacy bid events come out upgraded, so clients only ever see envelopes.
+func decodeEvent(msg *kafka.Message) (Event, error) {
+	contentType := header(msg, "content-type")
+	env, err := events.Unmarshal(msg.Value, contentType)
+	if err != nil {
+		return Event{}, err
 	}
+	// types added after this build are still routed, only known ones are checked
+	if _, err := env.Event(); err != nil && !errors.Is(err, events.ErrUnknownType) {
+		return Event{}, err
+	}
+	return newEvent(env)
 }
 
-func (h *Hub) Run() {
-	for {
-		select {
-
-		case conn := <-h.register:
-			h.clients[conn] = true
-			log.Println("Client connected. Total:", len(h.clients))
-
-		case conn := <-h.unregister:
-			if _, ok := h.clients[conn]; ok {
-				delete(h.clients, conn)
-				conn.Close()
-				log.Println("Client disconnected. Total:", len(h.clients))
-			}
-
-		case msg := <-h.broadcast:
-			for conn := range h.clients {
-				err := conn.WriteMessage(websocket.TextMessage, msg)
-				if err != nil {
-					conn.Close()
-					delete(h.clients, conn)
-				}
-			}
-		}
+// newEvent encodes an envelope once per wire format clients can ask for
+func newEvent(env events.Envelope) (Event, error) {
+	data, err := events.Marshal(env, events.ContentTypeJSON)
+	if err != nil {
+		return Event{}, err
 	}
+	proto, err := events.Marshal(env, events.ContentTypeProtobuf)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{Auction: env.AuctionID, Type: env.Type, Seq: env.Seq, Data: data, Proto: proto}, nil
 }
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true // allow all origins (dev only)
-	},
+// setupSnapshots connects to MySQL for subscribe snapshots, or returns nil when DB_DSN is not set
+func setupSnapshots(cfg Config) (SnapshotSource, error) {
+	if cfg.DBDSN == "" {
+		log.Println("DB_DSN not set, clients will not get an initial snapshot")
+		return nil, nil
+	}
+	db, err := sql.Open("mysql", cfg.DBDSN)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(10)
+	db.SetConnMaxLifetime(time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		return nil, err
+	}
+	return NewMySQLSnapshots(db, cfg.SnapshotBids), nil
 }
 
-func wsHandler(hub *Hub) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-
-		conn, err := upgrader.Upgrade(w, r, nil)
-		if err != nil {
-			log.Println("Upgrade error:", err)
-			return
+// header returns the value of a kafka message header, empty when it is missing
+func header(msg *kafka.Message, key string) string {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
 		}
-
-		hub.register <- conn
-
-		// Reader goroutine to detect disconnect
-		go func() {
-			defer func() {
-				hub.unregister <- conn
-			}()
-
-			for {
-				if _, _, err := conn.ReadMessage(); err != nil {
-					break
-				}
-			}
-		}()
 	}
+	return ""
 }
 
 func main() {
 
+	cfg := LoadConfig()
+
+	snapshots, err := setupSnapshots(cfg)
+	if err != nil {
+		panic(err)
+	}
+
 	// Create hub
-	hub := NewHub()
+	hub := NewHub(cfg.SlowConsumer, snapshots, cfg.ReplayBuffer)
 	go hub.Run()
 
 	// Kafka consumer
+	// Every instance has its own consumer group, so each replica receives every partition of
+	// the bids topic instead of splitting them with the others. Offsets are not committed since
+	// a new instance starts from the latest event and clients catch up through snapshots.
+	// Events are keyed by auction id, so all events of one auction arrive in order from a single partition.
+	log.Printf("Pisces instance %s consuming with group %s", cfg.InstanceID, cfg.GroupID)
 	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
-		"bootstrap.servers": "kafka:9092",
-		"group.id":          "auction-ws-group",
-		"auto.offset.reset": "latest",
+		"bootstrap.servers":  cfg.KafkaBrokers,
+		"group.id":           cfg.GroupID,
+		"auto.offset.reset":  "latest",
+		"enable.auto.commit": false,
 	})
 	if err != nil {
 		panic(err)
 	}
 	defer consumer.Close()
 
-	err = consumer.SubscribeTopics([]string{"bids"}, nil)
+	err = consumer.SubscribeTopics([]string{events.Topic}, nil)
 	if err != nil {
 		panic(err)
 	}
@@ -114,8 +115,15 @@ func main() {
 		for {
 			msg, err := consumer.ReadMessage(100)
 			if err == nil {
-				log.Printf("Kafka received: %s\n", string(msg.Value))
-				hub.broadcast <- msg.Value
+				log.Printf("Kafka received [%s v%s trace=%s partition=%d]: %s\n",
+					header(msg, "event-type"), header(msg, "schema-version"), header(msg, "trace-id"),
+					msg.TopicPartition.Partition, string(msg.Value))
+				ev, err := decodeEvent(msg)
+				if err != nil {
+					log.Printf("Dropping invalid event: %v", err)
+					continue
+				}
+				hub.broadcast <- ev
 			} else {
 				if kafkaErr, ok := err.(kafka.Error); ok && kafkaErr.Code() != kafka.ErrTimedOut {
 					log.Println("Kafka error:", err)
@@ -125,11 +133,13 @@ func main() {
 	}()
 
 	// HTTP server
-	http.HandleFunc("/ws", wsHandler(hub))
+	http.HandleFunc("/ws", wsHandler(hub, cfg))
 
+	// connection count is on /debug/vars via expvar
+	server := &http.Server{Addr: ":8081"}
 	go func() {
 		log.Println("WebSocket server running on :8081")
-		if err := http.ListenAndServe(":8081", nil); err != nil {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatal(err)
 		}
 	}()
@@ -142,5 +152,10 @@ func main() {
 	fmt.Println("Received signal:", sig)
 	fmt.Println("Shutting down...")
 
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.Shutdown(ctx)
+	hub.Shutdown()
+
 	consumer.Close()
 }
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gemini/bidding"
+	"gemini/events"
+	"time"
+)
+
+// SnapshotSource builds the message a client receives when it subscribes to an auction.
+// The envelope's seq is the auction's event sequence number the snapshot reflects, events at or below it are not sent after it.
+type SnapshotSource interface {
+	Snapshot(auction string) (events.Envelope, error)
+}
+
+// TypeSnapshot is the envelope type of snapshots. Snapshots only exist between Pisces and its clients.
+const TypeSnapshot = "auction.snapshot"
+
+// Snapshot is sent once per subscription, before any live event of that auction.
+// It goes out in the same envelope as the events, with the seq it reflects.
+type Snapshot struct {
+	Status string  `json:"status"`
+	Price  float64 `json:"price"`
+	Leader *string `json:"leader"`
+	// StartTime is set for auctions that open later or opened on a schedule
+	StartTime *time.Time `json:"start_time,omitempty"`
+	EndTime   time.Time  `json:"end_time"`
+	// MinNextBid is the lowest bid accepted next, only set while bidding is open or about to open
+	MinNextBid float64       `json:"min_next_bid,omitempty"`
+	Bids       []SnapshotBid `json:"bids"`
+}
+
+type SnapshotBid struct {
+	BidID    uint64    `json:"bid_id"`
+	Bidder   string    `json:"bidder"`
+	Price    float64   `json:"price"`
+	PlacedAt time.Time `json:"placed_at"`
+}
+
+func (Snapshot) EventType() string { return TypeSnapshot }
+
+func (s Snapshot) Validate() error {
+	if s.EndTime.IsZero() {
+		return errors.New("end_time is missing")
+	}
+	return nil
+}
+
+// MySQLSnapshots reads auction state straight from the tauras database
+type MySQLSnapshots struct {
+	db       *sql.DB
+	lastBids int
+}
+
+func NewMySQLSnapshots(db *sql.DB, lastBids int) *MySQLSnapshots {
+	return &MySQLSnapshots{db: db, lastBids: lastBids}
+}
+
+func (s *MySQLSnapshots) Snapshot(auction string) (events.Envelope, error) {
+	snap := Snapshot{Bids: []SnapshotBid{}}
+	var seq uint64
+
+	// one read-only transaction so price and bids come from the same point in time
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return events.Envelope{}, err
+	}
+	defer tx.Rollback()
+
+	var (
+		sellerID  uint64
+		startTime sql.NullTime
+		rule      sql.NullString
+	)
+	err = tx.QueryRow(
+		`SELECT user_id, status, COALESCE(current_price, starting_price), start_time, end_time, event_seq, increment_rule FROM auctions WHERE id = ?`,
+		auction,
+	).Scan(&sellerID, &snap.Status, &snap.Price, &startTime, &snap.EndTime, &seq, &rule)
+	if err != nil {
+		return events.Envelope{}, err
+	}
+	if snap.Status == "live" || snap.Status == "scheduled" {
+		increments, err := bidding.ParseIncrementRule(rule.String)
+		if err != nil {
+			return events.Envelope{}, err
+		}
+		snap.MinNextBid = increments.MinNextBid(snap.Price)
+	}
+	snap.EndTime = snap.EndTime.UTC()
+	if startTime.Valid {
+		st := startTime.Time.UTC()
+		snap.StartTime = &st
+	}
+
+	// the seller's opening bid is not a real bid
+	rows, err := tx.Query(
+		`SELECT b.id, b.user_id, b.price, b.updated_at, COALESCE(u.display_name, '')
+		 FROM bids b LEFT JOIN users u ON u.id = b.user_id
+		 WHERE b.auction_id = ? AND b.user_id <> ?
+		 ORDER BY b.id DESC LIMIT ?`,
+		auction, sellerID, s.lastBids,
+	)
+	if err != nil {
+		return events.Envelope{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			b           SnapshotBid
+			userID      uint64
+			placedAt    sql.NullTime
+			displayName string
+		)
+		if err := rows.Scan(&b.BidID, &userID, &b.Price, &placedAt, &displayName); err != nil {
+			return events.Envelope{}, err
+		}
+		b.Bidder = events.Alias(userID, displayName)
+		if placedAt.Valid {
+			b.PlacedAt = placedAt.Time.UTC()
+		}
+		snap.Bids = append(snap.Bids, b)
+	}
+	if err := rows.Err(); err != nil {
+		return events.Envelope{}, err
+	}
+	// bids only ever go up, so the newest bid is the highest one
+	if len(snap.Bids) > 0 {
+		snap.Leader = &snap.Bids[0].Bidder
+	}
+
+	return events.New(auction, seq, snap)
+}
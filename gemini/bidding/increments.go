@@ -0,0 +1,113 @@
+// Package bidding holds the bidding rules shared by the API, which enforces them,
+// and the gateway, which reports them in snapshots.
+package bidding
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Increment rule types
+const (
+	// IncrementFixed raises every bid by at least Amount
+	IncrementFixed = "fixed"
+	// IncrementPercent raises every bid by at least Amount percent of the current price
+	IncrementPercent = "percent"
+	// IncrementTable picks the increment from the band the current price falls in
+	IncrementTable = "table"
+)
+
+// MaxBands bounds the size of an increment table
+const MaxBands = 20
+
+// IncrementRule decides how far the next bid has to go above the current price.
+// The zero rule only asks for a higher bid, at least one cent.
+type IncrementRule struct {
+	Type   string          `json:"type"`
+	Amount float64         `json:"amount,omitempty"`
+	Bands  []IncrementBand `json:"bands,omitempty"`
+}
+
+// IncrementBand applies Increment to prices from From up to the next band
+type IncrementBand struct {
+	From      float64 `json:"from"`
+	Increment float64 `json:"increment"`
+}
+
+func (r IncrementRule) Validate() error {
+	switch r.Type {
+	case "":
+		if r.Amount != 0 || len(r.Bands) != 0 {
+			return errors.New("increment type is missing")
+		}
+	case IncrementFixed:
+		if r.Amount < 0.01 {
+			return errors.New("fixed increment must be at least 0.01")
+		}
+	case IncrementPercent:
+		if r.Amount <= 0 || r.Amount > 100 {
+			return errors.New("percent increment must be above 0 and at most 100")
+		}
+	case IncrementTable:
+		if len(r.Bands) == 0 || len(r.Bands) > MaxBands {
+			return fmt.Errorf("increment table needs 1 to %d bands", MaxBands)
+		}
+		if r.Bands[0].From != 0 {
+			return errors.New("the first increment band must start at 0")
+		}
+		for i, b := range r.Bands {
+			if b.Increment < 0.01 {
+				return errors.New("band increments must be at least 0.01")
+			}
+			if i > 0 && b.From <= r.Bands[i-1].From {
+				return errors.New("increment bands must be in ascending order")
+			}
+		}
+	default:
+		return fmt.Errorf("unknown increment type %q", r.Type)
+	}
+	return nil
+}
+
+// MinNextBid returns the lowest bid accepted on top of price, in whole cents
+func (r IncrementRule) MinNextBid(price float64) float64 {
+	cents := math.Round(price * 100)
+	var step float64
+	switch r.Type {
+	case IncrementFixed:
+		step = math.Round(r.Amount * 100)
+	case IncrementPercent:
+		// rounded up, so the increment never falls short of the percentage
+		step = math.Ceil(cents*r.Amount/100 - 1e-9)
+	case IncrementTable:
+		for _, b := range r.Bands {
+			if price >= b.From {
+				step = math.Round(b.Increment * 100)
+			}
+		}
+	}
+	return (cents + max(step, 1)) / 100
+}
+
+// Encode returns the stored form of a rule, empty for the zero rule
+func (r IncrementRule) Encode() (string, error) {
+	if r.Type == "" {
+		return "", nil
+	}
+	b, err := json.Marshal(r)
+	return string(b), err
+}
+
+// ParseIncrementRule reads a rule stored by Encode
+func ParseIncrementRule(s string) (IncrementRule, error) {
+	var r IncrementRule
+	if s == "" {
+		return r, nil
+	}
+	if err := json.Unmarshal([]byte(s), &r); err != nil {
+		return IncrementRule{}, err
+	}
+	return r, r.Validate()
+}
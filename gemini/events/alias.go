@@ -0,0 +1,18 @@
+package events
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Alias is the public name of a user in events and snapshots, never the email or the raw id.
+// Users without a display name get a stable pseudonym derived from their id, 64 bits wide
+// so two users practically never share one.
+func Alias(userID uint64, displayName string) string {
+	if displayName != "" {
+		return displayName
+	}
+	sum := sha256.Sum256([]byte("bidder:" + strconv.FormatUint(userID, 10)))
+	return "bidder-" + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,138 @@
+// Package events defines the auction events Tauras publishes and Pisces broadcasts.
+//
+// Every event travels in an Envelope. The envelope carries the routing fields Pisces needs
+// (auction id and per-auction sequence number) so it can route events it does not understand,
+// while the typed payload is only decoded by code that knows the type.
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Topic is the kafka topic carrying every auction event, keyed by auction id.
+// It kept the name from when bids were the only event.
+const Topic = "bids"
+
+// Version is the envelope schema version written by this code.
+// Version 1 is the bare bid JSON that was published before envelopes existed, Decode upgrades it.
+const Version = 2
+
+var (
+	ErrUnknownType        = errors.New("unknown event type")
+	ErrUnsupportedVersion = errors.New("unsupported event version")
+)
+
+// Envelope wraps one domain event
+type Envelope struct {
+	Type       string          `json:"type"`
+	Version    int             `json:"version"`
+	ID         string          `json:"id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	AuctionID  string          `json:"auction_id"`
+	Seq        uint64          `json:"seq"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// Event is implemented by every typed payload
+type Event interface {
+	EventType() string
+	Validate() error
+}
+
+// New validates ev and wraps it in an envelope with a fresh id
+func New(auctionID string, seq uint64, ev Event) (Envelope, error) {
+	if err := ev.Validate(); err != nil {
+		return Envelope{}, fmt.Errorf("invalid %s event: %w", ev.EventType(), err)
+	}
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return Envelope{}, err
+	}
+	env := Envelope{
+		Type:       ev.EventType(),
+		Version:    Version,
+		ID:         newID(),
+		OccurredAt: time.Now().UTC(),
+		AuctionID:  auctionID,
+		Seq:        seq,
+		Payload:    payload,
+	}
+	return env, env.Validate()
+}
+
+// Validate checks the envelope fields. The payload is only checked by Event.
+func (e Envelope) Validate() error {
+	switch {
+	case e.Type == "":
+		return errors.New("type is missing")
+	case e.Version < 1:
+		return errors.New("version is missing")
+	case e.Version > Version:
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, e.Version)
+	case e.ID == "":
+		return errors.New("id is missing")
+	case e.OccurredAt.IsZero():
+		return errors.New("occurred_at is missing")
+	case e.AuctionID == "":
+		return errors.New("auction_id is missing")
+	case len(e.Payload) == 0:
+		return errors.New("payload is missing")
+	}
+	return nil
+}
+
+// Event decodes and validates the typed payload.
+// Types this code does not know return ErrUnknownType, the envelope itself is still usable.
+func (e Envelope) Event() (Event, error) {
+	var ev Event
+	switch e.Type {
+	case TypeBidPlaced:
+		ev = &BidPlaced{}
+	case TypeAuctionCreated:
+		ev = &AuctionCreated{}
+	case TypeAuctionStarted:
+		ev = &AuctionStarted{}
+	case TypeAuctionExtended:
+		ev = &AuctionExtended{}
+	case TypeAuctionClosed:
+		ev = &AuctionClosed{}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownType, e.Type)
+	}
+	if err := json.Unmarshal(e.Payload, ev); err != nil {
+		return nil, fmt.Errorf("decoding %s payload: %w", e.Type, err)
+	}
+	if err := ev.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s event: %w", e.Type, err)
+	}
+	return ev, nil
+}
+
+// Decode parses an envelope, upgrading version 1 bid events that were published without one
+func Decode(data []byte) (Envelope, error) {
+	var probe struct {
+		Type *string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return Envelope{}, err
+	}
+	if probe.Type == nil {
+		return decodeLegacyBid(data)
+	}
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return Envelope{}, err
+	}
+	return env, env.Validate()
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
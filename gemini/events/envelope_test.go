@@ -0,0 +1,68 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDecodeUpgradesLegacyBid(t *testing.T) {
+	env, err := Decode([]byte(`{"Bidid":7,"Auctionid":"42","Bidder":"bob","Price":12.5,"Timestamp":1700000000,"Seq":3}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.Type != TypeBidPlaced || env.Version != 1 || env.ID != "bid-7" || env.AuctionID != "42" || env.Seq != 3 {
+		t.Errorf("upgraded envelope = %+v", env)
+	}
+	if !env.OccurredAt.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("occurred_at = %v, want the bid timestamp", env.OccurredAt)
+	}
+
+	ev, err := env.Event()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bid, ok := ev.(*BidPlaced)
+	if !ok || bid.BidID != 7 || bid.Bidder != "bob" || bid.Price != 12.5 {
+		t.Errorf("payload = %+v", ev)
+	}
+
+	// a re-delivered legacy event keeps its id
+	again, _ := Decode([]byte(`{"Bidid":7,"Auctionid":"42","Bidder":"bob","Price":12.5,"Timestamp":1700000000,"Seq":3}`))
+	if again.ID != env.ID {
+		t.Errorf("ids %q and %q differ for the same bid", env.ID, again.ID)
+	}
+
+	if _, err := Decode([]byte(`{"Bidid":7,"Bidder":"bob","Price":12.5}`)); err == nil {
+		t.Error("legacy bid without an auction id was accepted")
+	}
+}
+
+func TestDecodeRejectsNewerVersion(t *testing.T) {
+	env, err := New("42", 1, BidPlaced{BidID: 1, Bidder: "alice", Price: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Version = Version + 1
+	data, _ := json.Marshal(env)
+
+	if _, err := Decode(data); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Decode = %v, want ErrUnsupportedVersion", err)
+	}
+}
+
+func TestDecodeKeepsUnknownType(t *testing.T) {
+	data := []byte(`{"type":"auction.flagged","version":2,"id":"f1","occurred_at":"2026-03-01T12:00:00Z",` +
+		`"auction_id":"42","seq":12,"payload":{"reason":"spam"}}`)
+	env, err := Decode(data)
+	if err != nil {
+		t.Fatalf("envelope with an unknown type rejected: %v", err)
+	}
+	if env.Type != "auction.flagged" || env.AuctionID != "42" || env.Seq != 12 {
+		t.Errorf("envelope = %+v", env)
+	}
+	if _, err := env.Event(); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("Event = %v, want ErrUnknownType", err)
+	}
+}
@@ -0,0 +1,45 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// legacyBid is the version 1 bid event, published as bare JSON before envelopes existed.
+// Outbox rows written by older Tauras builds can still carry it.
+type legacyBid struct {
+	Bidid     uint64
+	Auctionid string
+	Bidder    string
+	Price     float64
+	Timestamp int64
+	Seq       uint64
+}
+
+func decodeLegacyBid(data []byte) (Envelope, error) {
+	var old legacyBid
+	if err := json.Unmarshal(data, &old); err != nil {
+		return Envelope{}, err
+	}
+	if old.Auctionid == "" {
+		return Envelope{}, errors.New("legacy event without an auction id")
+	}
+	ev := BidPlaced{BidID: old.Bidid, Bidder: old.Bidder, Price: old.Price}
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return Envelope{}, err
+	}
+	env := Envelope{
+		Type:    TypeBidPlaced,
+		Version: 1,
+		// derived from the bid id so a re-delivered legacy event keeps the same id
+		ID:         "bid-" + strconv.FormatUint(old.Bidid, 10),
+		OccurredAt: time.Unix(old.Timestamp, 0).UTC(),
+		AuctionID:  old.Auctionid,
+		Seq:        old.Seq,
+		Payload:    payload,
+	}
+	return env, env.Validate()
+}
@@ -0,0 +1,401 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Content types of the two wire encodings, carried in the content-type kafka header.
+// Messages without the header are JSON.
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// Marshal encodes an envelope in the given content type
+func Marshal(env Envelope, contentType string) ([]byte, error) {
+	switch contentType {
+	case ContentTypeJSON, "":
+		return json.Marshal(env)
+	case ContentTypeProtobuf:
+		return marshalProto(env)
+	}
+	return nil, fmt.Errorf("unsupported content type %q", contentType)
+}
+
+// Unmarshal decodes an envelope in the given content type, JSON input goes through Decode
+func Unmarshal(data []byte, contentType string) (Envelope, error) {
+	switch contentType {
+	case ContentTypeJSON, "":
+		return Decode(data)
+	case ContentTypeProtobuf:
+		env, err := unmarshalProto(data)
+		if err != nil {
+			return Envelope{}, err
+		}
+		return env, env.Validate()
+	}
+	return Envelope{}, fmt.Errorf("unsupported content type %q", contentType)
+}
+
+// field numbers, see events.proto
+const (
+	envType        protowire.Number = 1
+	envVersion     protowire.Number = 2
+	envID          protowire.Number = 3
+	envOccurredAt  protowire.Number = 4
+	envAuctionID   protowire.Number = 5
+	envSeq         protowire.Number = 6
+	envBidPlaced   protowire.Number = 10
+	envCreated     protowire.Number = 11
+	envExtended    protowire.Number = 12
+	envClosed      protowire.Number = 13
+	envStarted     protowire.Number = 14
+	envJSONPayload protowire.Number = 15
+)
+
+// wireTypes are the wire types events.proto declares per field number of a message
+type wireTypes map[protowire.Number]protowire.Type
+
+var (
+	envelopeWire = wireTypes{
+		envType: protowire.BytesType, envVersion: protowire.VarintType, envID: protowire.BytesType,
+		envOccurredAt: protowire.BytesType, envAuctionID: protowire.BytesType, envSeq: protowire.VarintType,
+		envBidPlaced: protowire.BytesType, envCreated: protowire.BytesType, envExtended: protowire.BytesType,
+		envClosed: protowire.BytesType, envStarted: protowire.BytesType, envJSONPayload: protowire.BytesType,
+	}
+	bidPlacedWire = wireTypes{1: protowire.VarintType, 2: protowire.BytesType, 3: protowire.Fixed64Type, 4: protowire.Fixed64Type}
+	createdWire   = wireTypes{
+		1: protowire.BytesType, 2: protowire.BytesType, 3: protowire.Fixed64Type, 4: protowire.BytesType,
+		5: protowire.BytesType, 6: protowire.BytesType, 7: protowire.Fixed64Type,
+	}
+	startedWire   = wireTypes{1: protowire.BytesType, 2: protowire.BytesType, 3: protowire.Fixed64Type}
+	extendedWire  = wireTypes{1: protowire.BytesType, 2: protowire.BytesType, 3: protowire.Fixed64Type}
+	closedWire    = wireTypes{1: protowire.BytesType, 2: protowire.Fixed64Type, 3: protowire.BytesType, 4: protowire.BytesType}
+	timestampWire = wireTypes{1: protowire.VarintType, 2: protowire.VarintType}
+)
+
+// check rejects a known field that arrived with another wire type than declared,
+// which would otherwise be read as an empty or garbled value. Unknown fields pass.
+func (w wireTypes) check(num protowire.Number, typ protowire.Type) error {
+	if want, ok := w[num]; ok && typ != want {
+		return fmt.Errorf("field %d has wire type %d, want %d", num, typ, want)
+	}
+	return nil
+}
+
+func marshalProto(env Envelope) ([]byte, error) {
+	var b []byte
+	b = appendString(b, envType, env.Type)
+	if env.Version != 0 {
+		b = protowire.AppendTag(b, envVersion, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(int64(env.Version)))
+	}
+	b = appendString(b, envID, env.ID)
+	b = appendTime(b, envOccurredAt, env.OccurredAt)
+	b = appendString(b, envAuctionID, env.AuctionID)
+	if env.Seq != 0 {
+		b = protowire.AppendTag(b, envSeq, protowire.VarintType)
+		b = protowire.AppendVarint(b, env.Seq)
+	}
+
+	ev, err := env.Event()
+	if errors.Is(err, ErrUnknownType) {
+		return protowire.AppendBytes(protowire.AppendTag(b, envJSONPayload, protowire.BytesType), env.Payload), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var (
+		num  protowire.Number
+		body []byte
+	)
+	switch e := ev.(type) {
+	case *BidPlaced:
+		num = envBidPlaced
+		if e.BidID != 0 {
+			body = protowire.AppendTag(body, 1, protowire.VarintType)
+			body = protowire.AppendVarint(body, e.BidID)
+		}
+		body = appendString(body, 2, e.Bidder)
+		body = appendDouble(body, 3, e.Price)
+		body = appendDouble(body, 4, e.MinNextBid)
+	case *AuctionCreated:
+		num = envCreated
+		body = appendString(body, 1, e.Item)
+		body = appendString(body, 2, e.Seller)
+		body = appendDouble(body, 3, e.StartingPrice)
+		body = appendString(body, 4, e.ImageURL)
+		body = appendTime(body, 5, e.EndTime)
+		if e.StartTime != nil {
+			body = appendTime(body, 6, *e.StartTime)
+		}
+		body = appendDouble(body, 7, e.MinNextBid)
+	case *AuctionStarted:
+		num = envStarted
+		body = appendTime(body, 1, e.StartTime)
+		body = appendTime(body, 2, e.EndTime)
+		body = appendDouble(body, 3, e.MinNextBid)
+	case *AuctionExtended:
+		num = envExtended
+		body = appendTime(body, 1, e.PreviousEndTime)
+		body = appendTime(body, 2, e.EndTime)
+		body = appendDouble(body, 3, e.MinNextBid)
+	case *AuctionClosed:
+		num = envClosed
+		// optional fields are written even when zero so presence survives the round trip
+		if e.Winner != nil {
+			body = protowire.AppendTag(body, 1, protowire.BytesType)
+			body = protowire.AppendString(body, *e.Winner)
+		}
+		if e.Price != nil {
+			body = protowire.AppendTag(body, 2, protowire.Fixed64Type)
+			body = protowire.AppendFixed64(body, math.Float64bits(*e.Price))
+		}
+		body = appendString(body, 3, e.Outcome)
+		body = appendString(body, 4, e.Reason)
+	default:
+		return nil, fmt.Errorf("no protobuf encoding for %T", ev)
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, body), nil
+}
+
+// unmarshalProto decodes the wire format back into an envelope with a JSON payload
+func unmarshalProto(b []byte) (Envelope, error) {
+	var (
+		env     Envelope
+		payload Event
+	)
+	err := walk(b, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+		if err := envelopeWire.check(num, typ); err != nil {
+			return err
+		}
+		switch num {
+		case envType:
+			env.Type = string(v)
+		case envVersion:
+			env.Version = int(int32(x))
+		case envID:
+			env.ID = string(v)
+		case envOccurredAt:
+			t, err := parseTime(v)
+			env.OccurredAt = t
+			return err
+		case envAuctionID:
+			env.AuctionID = string(v)
+		case envSeq:
+			env.Seq = x
+		case envBidPlaced:
+			e := &BidPlaced{}
+			payload = e
+			return walk(v, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+				if err := bidPlacedWire.check(num, typ); err != nil {
+					return err
+				}
+				switch num {
+				case 1:
+					e.BidID = x
+				case 2:
+					e.Bidder = string(v)
+				case 3:
+					e.Price = math.Float64frombits(x)
+				case 4:
+					e.MinNextBid = math.Float64frombits(x)
+				}
+				return nil
+			})
+		case envCreated:
+			e := &AuctionCreated{}
+			payload = e
+			return walk(v, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+				if err := createdWire.check(num, typ); err != nil {
+					return err
+				}
+				var err error
+				switch num {
+				case 1:
+					e.Item = string(v)
+				case 2:
+					e.Seller = string(v)
+				case 3:
+					e.StartingPrice = math.Float64frombits(x)
+				case 4:
+					e.ImageURL = string(v)
+				case 5:
+					e.EndTime, err = parseTime(v)
+				case 6:
+					var t time.Time
+					t, err = parseTime(v)
+					e.StartTime = &t
+				case 7:
+					e.MinNextBid = math.Float64frombits(x)
+				}
+				return err
+			})
+		case envStarted:
+			e := &AuctionStarted{}
+			payload = e
+			return walk(v, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+				if err := startedWire.check(num, typ); err != nil {
+					return err
+				}
+				var err error
+				switch num {
+				case 1:
+					e.StartTime, err = parseTime(v)
+				case 2:
+					e.EndTime, err = parseTime(v)
+				case 3:
+					e.MinNextBid = math.Float64frombits(x)
+				}
+				return err
+			})
+		case envExtended:
+			e := &AuctionExtended{}
+			payload = e
+			return walk(v, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+				if err := extendedWire.check(num, typ); err != nil {
+					return err
+				}
+				var err error
+				switch num {
+				case 1:
+					e.PreviousEndTime, err = parseTime(v)
+				case 2:
+					e.EndTime, err = parseTime(v)
+				case 3:
+					e.MinNextBid = math.Float64frombits(x)
+				}
+				return err
+			})
+		case envClosed:
+			e := &AuctionClosed{}
+			payload = e
+			return walk(v, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+				if err := closedWire.check(num, typ); err != nil {
+					return err
+				}
+				switch num {
+				case 1:
+					w := string(v)
+					e.Winner = &w
+				case 2:
+					p := math.Float64frombits(x)
+					e.Price = &p
+				case 3:
+					e.Outcome = string(v)
+				case 4:
+					e.Reason = string(v)
+				}
+				return nil
+			})
+		case envJSONPayload:
+			env.Payload = append(json.RawMessage(nil), v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return Envelope{}, err
+	}
+	if payload != nil {
+		if env.Payload, err = json.Marshal(payload); err != nil {
+			return Envelope{}, err
+		}
+	}
+	return env, nil
+}
+
+// walk calls fn for every field of a message. Length-delimited values arrive in v,
+// varint and fixed values in x. Callers check known fields against their wireTypes and skip unknown ones.
+func walk(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		var (
+			v []byte
+			x uint64
+		)
+		switch typ {
+		case protowire.VarintType:
+			x, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			x, n = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			var x32 uint32
+			x32, n = protowire.ConsumeFixed32(b)
+			x = uint64(x32)
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if err := fn(num, typ, v, x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendDouble(b []byte, num protowire.Number, f float64) []byte {
+	if f == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(f))
+}
+
+// appendTime writes a google.protobuf.Timestamp
+func appendTime(b []byte, num protowire.Number, t time.Time) []byte {
+	if t.IsZero() {
+		return b
+	}
+	var ts []byte
+	if s := t.Unix(); s != 0 {
+		ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(s))
+	}
+	if ns := t.Nanosecond(); ns != 0 {
+		ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(ns))
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, ts)
+}
+
+func parseTime(b []byte) (time.Time, error) {
+	var secs, nanos int64
+	err := walk(b, func(num protowire.Number, typ protowire.Type, _ []byte, x uint64) error {
+		if err := timestampWire.check(num, typ); err != nil {
+			return err
+		}
+		switch num {
+		case 1:
+			secs = int64(x)
+		case 2:
+			nanos = int64(int32(x))
+		}
+		return nil
+	})
+	return time.Unix(secs, nanos).UTC(), err
+}
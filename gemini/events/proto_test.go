@@ -0,0 +1,277 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func mustNew(t *testing.T, seq uint64, ev Event) Envelope {
+	t.Helper()
+	env, err := New("42", seq, ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return env
+}
+
+func protoCases(t *testing.T) map[string]Envelope {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(2*time.Hour + 500*time.Millisecond)
+	winner, price, zero := "bidder-abc", 99.5, 0.0
+
+	legacy, err := Decode([]byte(`{"Bidid":7,"Auctionid":"42","Bidder":"bob","Price":12.5,"Timestamp":1700000000,"Seq":3}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]Envelope{
+		"bid placed": mustNew(t, 5, BidPlaced{BidID: 11, Bidder: "alice", Price: 10.25, MinNextBid: 11}),
+		// seq 0 and a missing min_next_bid are left off the wire and must come back as zero
+		"bid placed zero values": mustNew(t, 0, BidPlaced{BidID: 1, Bidder: "a", Price: 0.01}),
+		"legacy bid":             legacy,
+		"auction created": mustNew(t, 1, AuctionCreated{
+			Item: "lamp", Seller: "carol", StartingPrice: 5, ImageURL: "https://example.com/lamp.png",
+			StartTime: &start, EndTime: end, MinNextBid: 6,
+		}),
+		"auction created without start time": mustNew(t, 1, AuctionCreated{Item: "lamp", Seller: "carol", EndTime: end}),
+		"auction started":                    mustNew(t, 2, AuctionStarted{StartTime: start, EndTime: end, MinNextBid: 6}),
+		"auction extended":                   mustNew(t, 9, AuctionExtended{PreviousEndTime: start, EndTime: end}),
+		"auction closed sold": mustNew(t, 10, AuctionClosed{
+			Winner: &winner, Price: &price, Outcome: OutcomeSold, Reason: ReasonBuyNow,
+		}),
+		// a zero price is still present, unlike an unsold auction's missing one
+		"auction closed zero price":      mustNew(t, 10, AuctionClosed{Winner: &winner, Price: &zero, Outcome: OutcomeSold}),
+		"auction closed unsold":          mustNew(t, 10, AuctionClosed{Outcome: OutcomeUnsold, Reason: ReasonEnded}),
+		"auction closed before outcomes": mustNew(t, 10, AuctionClosed{}),
+		"unknown type": {
+			Type:       "auction.flagged",
+			Version:    Version,
+			ID:         "f1",
+			OccurredAt: start,
+			AuctionID:  "42",
+			Seq:        12,
+			Payload:    json.RawMessage(`{"reason":"spam","count":2}`),
+		},
+	}
+}
+
+func TestProtoRoundTrip(t *testing.T) {
+	for name, env := range protoCases(t) {
+		t.Run(name, func(t *testing.T) {
+			data, err := Marshal(env, ContentTypeProtobuf)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			got, err := Unmarshal(data, ContentTypeProtobuf)
+			if err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+
+			want, _ := json.Marshal(env)
+			back, _ := json.Marshal(got)
+			if !bytes.Equal(want, back) {
+				t.Errorf("round trip changed the envelope\n got: %s\nwant: %s", back, want)
+			}
+			if _, err := got.Event(); err != nil && !errors.Is(err, ErrUnknownType) {
+				t.Errorf("decoded payload is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestProtoUnknownTypeUsesJSONPayload(t *testing.T) {
+	env := protoCases(t)["unknown type"]
+	data, err := Marshal(env, ContentTypeProtobuf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	walk(data, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) error {
+		if num == envJSONPayload {
+			found = bytes.Equal(v, env.Payload)
+		}
+		return nil
+	})
+	if !found {
+		t.Error("unknown type was not carried as json_payload")
+	}
+}
+
+func TestProtoUnmarshalRejectsBadInput(t *testing.T) {
+	data, err := Marshal(protoCases(t)["bid placed"], ContentTypeProtobuf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Unmarshal(data[:len(data)-3], ContentTypeProtobuf); err == nil {
+		t.Error("truncated message was accepted")
+	}
+	if _, err := Unmarshal(data, "text/plain"); err == nil {
+		t.Error("unknown content type was accepted")
+	}
+}
+
+func TestProtoUnmarshalRejectsWrongWireType(t *testing.T) {
+	valid, err := Marshal(protoCases(t)["bid placed"], ContentTypeProtobuf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a field repeated later in the message overrides the valid one
+	field := func(num protowire.Number, typ protowire.Type, v []byte) []byte {
+		b := protowire.AppendTag(slices.Clone(valid), num, typ)
+		if typ == protowire.VarintType {
+			return protowire.AppendVarint(b, 1)
+		}
+		return protowire.AppendBytes(b, v)
+	}
+	bid := protowire.AppendString(protowire.AppendTag(nil, 2, protowire.BytesType), "alice")
+	bid = protowire.AppendVarint(protowire.AppendTag(bid, 3, protowire.VarintType), 10)          // price is a double
+	ts := protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), "1700000000") // seconds are a varint
+
+	for name, msg := range map[string][]byte{
+		"envelope":          field(envSeq, protowire.BytesType, []byte("5")),
+		"payload":           field(envBidPlaced, protowire.BytesType, bid),
+		"timestamp":         field(envOccurredAt, protowire.BytesType, ts),
+		"message as scalar": field(envBidPlaced, protowire.VarintType, nil),
+	} {
+		if _, err := Unmarshal(msg, ContentTypeProtobuf); err == nil {
+			t.Errorf("%s: mismatched wire type was accepted", name)
+		}
+	}
+
+	// unknown fields are still skipped whatever their type
+	if _, err := Unmarshal(field(99, protowire.VarintType, nil), ContentTypeProtobuf); err != nil {
+		t.Errorf("unknown field rejected: %v", err)
+	}
+}
+
+// protoField is one field of a message in events.proto
+type protoField struct {
+	name string
+	typ  string
+}
+
+// readSchema parses the message definitions of events.proto into field number -> field per message
+func readSchema(t *testing.T) map[string]map[protowire.Number]protoField {
+	t.Helper()
+	src, err := os.ReadFile("events.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := regexp.MustCompile(`(?s)message (\w+) \{(.*?)\n\}`).FindAllSubmatch(src, -1)
+	field := regexp.MustCompile(`(?m)^\s*(?:optional\s+)?([\w.]+)\s+(\w+)\s*=\s*(\d+);`)
+	schema := map[string]map[protowire.Number]protoField{}
+	for _, m := range messages {
+		fields := map[protowire.Number]protoField{}
+		for _, f := range field.FindAllSubmatch(m[2], -1) {
+			num, _ := strconv.Atoi(string(f[3]))
+			if _, dup := fields[protowire.Number(num)]; dup {
+				t.Fatalf("%s reuses field number %d", m[1], num)
+			}
+			fields[protowire.Number(num)] = protoField{name: string(f[2]), typ: string(f[1])}
+		}
+		schema[string(m[1])] = fields
+	}
+	return schema
+}
+
+// TestProtoMatchesSchema checks the hand-written codec against events.proto: every field it writes
+// must be declared there under the same number, with the same name as the JSON field it carries.
+func TestProtoMatchesSchema(t *testing.T) {
+	schema := readSchema(t)
+	for name, env := range protoCases(t) {
+		t.Run(name, func(t *testing.T) {
+			data, err := Marshal(env, ContentTypeProtobuf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var payloadNames, wantNames []string
+			err = walk(data, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) error {
+				f, ok := schema["Envelope"][num]
+				if !ok {
+					t.Errorf("envelope field %d is not in events.proto", num)
+					return nil
+				}
+				msg, ok := schema[f.typ]
+				if !ok {
+					return nil
+				}
+				return walk(v, func(num protowire.Number, _ protowire.Type, _ []byte, _ uint64) error {
+					pf, ok := msg[num]
+					if !ok {
+						t.Errorf("%s field %d is not in events.proto", f.typ, num)
+						return nil
+					}
+					payloadNames = append(payloadNames, pf.name)
+					return nil
+				})
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if env.Type == "auction.flagged" {
+				return
+			}
+
+			// every non-empty JSON field has to have travelled under its proto name
+			var payload map[string]any
+			if err := json.Unmarshal(env.Payload, &payload); err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range payload {
+				if v == nil || v == "" || (v == 0.0 && k != "price") {
+					continue
+				}
+				wantNames = append(wantNames, k)
+			}
+			slices.Sort(payloadNames)
+			slices.Sort(wantNames)
+			if !slices.Equal(payloadNames, wantNames) {
+				t.Errorf("wire fields %v, want %v", payloadNames, wantNames)
+			}
+		})
+	}
+}
+
+// TestProtoWireTypesMatchSchema checks the wire types the decoder expects against the field types in events.proto
+func TestProtoWireTypesMatchSchema(t *testing.T) {
+	schema := readSchema(t)
+	decoders := map[string]wireTypes{
+		"Envelope":        envelopeWire,
+		"BidPlaced":       bidPlacedWire,
+		"AuctionCreated":  createdWire,
+		"AuctionStarted":  startedWire,
+		"AuctionExtended": extendedWire,
+		"AuctionClosed":   closedWire,
+	}
+	for name, fields := range schema {
+		wire, ok := decoders[name]
+		if !ok {
+			t.Errorf("no wire types for message %s", name)
+			continue
+		}
+		if len(wire) != len(fields) {
+			t.Errorf("%s: %d wire types for %d fields", name, len(wire), len(fields))
+		}
+		for num, f := range fields {
+			want := protowire.BytesType // strings, bytes and messages
+			switch f.typ {
+			case "double":
+				want = protowire.Fixed64Type
+			case "int32", "int64", "uint32", "uint64", "bool":
+				want = protowire.VarintType
+			}
+			if got, ok := wire[num]; !ok || got != want {
+				t.Errorf("%s.%s (%d) decoded as wire type %d, want %d", name, f.name, num, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	TypeBidPlaced       = "bid.placed"
+	TypeAuctionCreated  = "auction.created"
+	TypeAuctionStarted  = "auction.started"
+	TypeAuctionExtended = "auction.extended"
+	TypeAuctionClosed   = "auction.closed"
+)
+
+// BidPlaced is published for every accepted bid.
+// Bidders only ever appear by their public alias.
+type BidPlaced struct {
+	BidID  uint64  `json:"bid_id"`
+	Bidder string  `json:"bidder"`
+	Price  float64 `json:"price"`
+	// MinNextBid is the lowest bid accepted after this one, 0 in events from before increments existed
+	MinNextBid float64 `json:"min_next_bid,omitempty"`
+}
+
+func (BidPlaced) EventType() string { return TypeBidPlaced }
+
+func (e BidPlaced) Validate() error {
+	switch {
+	case e.BidID == 0:
+		return errors.New("bid_id is missing")
+	case e.Bidder == "":
+		return errors.New("bidder is missing")
+	case e.Price <= 0:
+		return errors.New("price must be positive")
+	case e.MinNextBid != 0 && e.MinNextBid <= e.Price:
+		return errors.New("min_next_bid must be above price")
+	}
+	return nil
+}
+
+// AuctionCreated is published when a seller lists an item
+type AuctionCreated struct {
+	Item          string  `json:"item"`
+	Seller        string  `json:"seller"`
+	StartingPrice float64 `json:"starting_price"`
+	ImageURL      string  `json:"image_url,omitempty"`
+	// StartTime is set for auctions scheduled to open later, they open with AuctionStarted
+	StartTime  *time.Time `json:"start_time,omitempty"`
+	EndTime    time.Time  `json:"end_time"`
+	MinNextBid float64    `json:"min_next_bid,omitempty"`
+}
+
+func (AuctionCreated) EventType() string { return TypeAuctionCreated }
+
+func (e AuctionCreated) Validate() error {
+	switch {
+	case e.Item == "":
+		return errors.New("item is missing")
+	case e.Seller == "":
+		return errors.New("seller is missing")
+	case e.StartingPrice < 0:
+		return errors.New("starting_price must not be negative")
+	case e.EndTime.IsZero():
+		return errors.New("end_time is missing")
+	case e.StartTime != nil && !e.EndTime.After(*e.StartTime):
+		return errors.New("end_time must be after start_time")
+	case e.MinNextBid != 0 && e.MinNextBid <= e.StartingPrice:
+		return errors.New("min_next_bid must be above starting_price")
+	}
+	return nil
+}
+
+// AuctionStarted is published when a scheduled auction opens for bids
+type AuctionStarted struct {
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time"`
+	MinNextBid float64   `json:"min_next_bid,omitempty"`
+}
+
+func (AuctionStarted) EventType() string { return TypeAuctionStarted }
+
+func (e AuctionStarted) Validate() error {
+	if e.StartTime.IsZero() || !e.EndTime.After(e.StartTime) {
+		return errors.New("end_time must be after start_time")
+	}
+	return nil
+}
+
+// AuctionExtended is published when an auction's end time moves later
+type AuctionExtended struct {
+	PreviousEndTime time.Time `json:"previous_end_time"`
+	EndTime         time.Time `json:"end_time"`
+	MinNextBid      float64   `json:"min_next_bid,omitempty"`
+}
+
+func (AuctionExtended) EventType() string { return TypeAuctionExtended }
+
+func (e AuctionExtended) Validate() error {
+	if e.EndTime.IsZero() || !e.EndTime.After(e.PreviousEndTime) {
+		return errors.New("end_time must be after previous_end_time")
+	}
+	return nil
+}
+
+// Outcomes of a closed auction
+const (
+	OutcomeSold = "sold"
+	// OutcomeUnsold auctions had no bids, or none that met the reserve price
+	OutcomeUnsold = "unsold"
+)
+
+// Reasons an auction closed
+const (
+	// ReasonEnded auctions reached their end time
+	ReasonEnded = "ended"
+	// ReasonBuyNow auctions were bought at their buy-now price
+	ReasonBuyNow = "buy_now"
+)
+
+// AuctionClosed is published once when an auction ends.
+// Winner and Price are empty when the auction went unsold.
+type AuctionClosed struct {
+	Winner  *string  `json:"winner"`
+	Price   *float64 `json:"price"`
+	Outcome string   `json:"outcome"`
+	Reason  string   `json:"reason,omitempty"`
+}
+
+func (AuctionClosed) EventType() string { return TypeAuctionClosed }
+
+func (e AuctionClosed) Validate() error {
+	if (e.Winner == nil) != (e.Price == nil) {
+		return errors.New("winner and price must be set together")
+	}
+	switch e.Outcome {
+	case OutcomeSold:
+		if e.Winner == nil {
+			return errors.New("sold auctions need a winner")
+		}
+	case OutcomeUnsold:
+		if e.Winner != nil {
+			return errors.New("unsold auctions have no winner")
+		}
+	case "":
+		// events from before outcomes existed
+	default:
+		return fmt.Errorf("unknown outcome %q", e.Outcome)
+	}
+	if e.Reason == ReasonBuyNow && e.Outcome != OutcomeSold {
+		return errors.New("buy-now closes are always sold")
+	}
+	return nil
+}
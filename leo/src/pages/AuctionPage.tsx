@@ -12,9 +12,67 @@ type Auction = {
   startingPrice: number;
   imageUrl: string | null;
   endTime: string;
+  isHighestBidder?: boolean;
+  status: string;
+  winner?: string;
+  startTime?: string;
+  softCloseMinutes: number;
+  extensionMinutes: number;
+  hasReserve: boolean;
+  reserveMet?: boolean;
+  outcome?: "sold" | "unsold";
+  buyNowPrice?: number;
+  buyNowAvailable?: boolean;
+  closeReason?: "ended" | "buy_now";
+  minNextBid?: number;
 };
 
-type BidEntry = { price: number; label: string };
+type BidEntry = { id?: number; price: number; label: string };
+
+type EventEnvelope = {
+  type: string;
+  version: number;
+  id: string;
+  occurred_at: string;
+  auction_id: string;
+  seq: number;
+  payload: unknown;
+};
+
+type BidPlacedPayload = {
+  bid_id: number;
+  bidder: string;
+  price: number;
+  min_next_bid?: number;
+};
+
+type AuctionClosedPayload = {
+  winner: string | null;
+  price: number | null;
+  outcome: "sold" | "unsold";
+  reason?: "ended" | "buy_now";
+};
+
+type AuctionStartedPayload = {
+  start_time: string;
+  end_time: string;
+  min_next_bid?: number;
+};
+
+type AuctionExtendedPayload = {
+  previous_end_time: string;
+  end_time: string;
+  min_next_bid?: number;
+};
+
+type SnapshotPayload = {
+  status: string;
+  price: number;
+  leader: string | null;
+  end_time: string;
+  min_next_bid?: number;
+  bids: { bid_id: number; bidder: string; price: number; placed_at: string }[];
+};
 
 export function AuctionPage() {
   const { id } = useParams<{ id: string }>();
@@ -24,9 +82,10 @@ export function AuctionPage() {
   const [wsStatus, setWsStatus] = useState<
     "disconnected" | "connecting" | "connected"
   >("disconnected");
-  const [userId, setUserId] = useState("gambler");
   const [currentPrice, setCurrentPrice] = useState<number>(0);
   const [bidPrice, setBidPrice] = useState<number>(0);
+  // Lowest bid the server accepts next, from the auction's increment rule
+  const [minBid, setMinBid] = useState<number>(0);
   const [bidError, setBidError] = useState<string | null>(null);
   const [bidLoading, setBidLoading] = useState(false);
   const [bids, setBids] = useState<BidEntry[]>([]);
@@ -57,8 +116,10 @@ export function AuctionPage() {
           setAuction(auctionData);
           const price =
             auctionData.currentPrice ?? auctionData.startingPrice ?? 0;
+          const min = auctionData.minNextBid ?? price + 0.01;
           setCurrentPrice(price);
-          setBidPrice(price);
+          setMinBid(min);
+          setBidPrice(min);
         }
       } catch (err) {
         console.error(err);
@@ -75,16 +136,19 @@ export function AuctionPage() {
     };
   }, [id]);
 
-  // Countdown timer
+  // Countdown timer, to the start while the auction is scheduled and to the end after
   useEffect(() => {
     if (!auction) return;
-    const end = new Date(auction.endTime).getTime();
+    const scheduled = auction.status === "scheduled" && !!auction.startTime;
+    const end = new Date(
+      scheduled ? auction.startTime! : auction.endTime
+    ).getTime();
 
     function update() {
       const now = Date.now();
       const diff = end - now;
       if (diff <= 0) {
-        setTimeLeft("Auction ended");
+        setTimeLeft(scheduled ? "Starting..." : "Auction ended");
         return;
       }
       const totalSeconds = Math.floor(diff / 1000);
@@ -104,13 +168,17 @@ export function AuctionPage() {
     if (!id) return;
     setWsStatus("connecting");
 
-    const wsUrl = `${WS_BASE}/ws`;
+    // last per-auction sequence number seen, used to resume without gaps after a reconnect
+    let lastSeq: number | null = null;
 
     let socket: WebSocket | null = null;
     let reconnectTimer: ReturnType<typeof setTimeout> | null = null;
 
     const connectWS = () => {
-      socket = new WebSocket(wsUrl);
+      const since = lastSeq !== null ? `&since=${lastSeq}` : "";
+      socket = new WebSocket(
+        `${WS_BASE}/ws?auction=${encodeURIComponent(id)}${since}`
+      );
 
       socket.onopen = () => {
         setWsStatus("connected");
@@ -118,32 +186,91 @@ export function AuctionPage() {
 
       socket.onmessage = (event) => {
         try {
-          const data = JSON.parse(event.data) as {
-            Auctionid?: string;
-            Price?: number;
-            email?: string;
-          };
-
-          // Only handle bids for this auction
-          if (!data.Auctionid || data.Auctionid !== id) {
+          // Every message is an event envelope, see gemini/events
+          const data = JSON.parse(event.data) as EventEnvelope;
+
+          // Only handle events for this auction
+          if (!data.auction_id || data.auction_id !== id) {
+            return;
+          }
+
+          // Skip events we already have
+          if (
+            data.type !== "auction.snapshot" &&
+            lastSeq !== null &&
+            data.seq <= lastSeq
+          ) {
+            return;
+          }
+          lastSeq = data.seq;
+
+          // Initial state sent by Pisces when we subscribe
+          if (data.type === "auction.snapshot") {
+            const snap = data.payload as SnapshotPayload;
+            setAuction((prev) => (prev ? { ...prev, status: snap.status } : prev));
+            const min = snap.min_next_bid ?? snap.price + 0.01;
+            setCurrentPrice(snap.price);
+            setMinBid(min);
+            setBidPrice(min);
+            setBids(
+              (snap.bids ?? []).map((b) => ({
+                id: b.bid_id,
+                price: b.price,
+                label: `${b.bidder} bid ${b.price.toFixed(2)}`,
+              }))
+            );
             return;
           }
 
-          if (data.Price !== undefined) {
-            const price = data.Price!;
-            setCurrentPrice(price);
-            setBidPrice(price);
+          if (data.type === "auction.started") {
+            const started = data.payload as AuctionStartedPayload;
+            if (started.min_next_bid) setMinBid(started.min_next_bid);
+            setAuction((prev) =>
+              prev ? { ...prev, status: "live", endTime: started.end_time } : prev
+            );
+            return;
+          }
 
-            const label = `anonymous bid, ${price.toFixed(2)}`;
-            const next: BidEntry = { price, label };
+          // A late bid pushed the end out (soft close)
+          if (data.type === "auction.extended") {
+            const extended = data.payload as AuctionExtendedPayload;
+            if (extended.min_next_bid) setMinBid(extended.min_next_bid);
+            setAuction((prev) =>
+              prev ? { ...prev, endTime: extended.end_time } : prev
+            );
+            return;
+          }
+
+          if (data.type === "auction.closed") {
+            const closed = data.payload as AuctionClosedPayload;
+            setAuction((prev) =>
+              prev
+                ? {
+                    ...prev,
+                    status: "closed",
+                    winner: closed.winner ?? undefined,
+                    outcome: closed.outcome,
+                    closeReason: closed.reason,
+                    buyNowAvailable: false,
+                  }
+                : prev
+            );
+            return;
+          }
+
+          if (data.type === "bid.placed") {
+            const bid = data.payload as BidPlacedPayload;
+            const min = bid.min_next_bid ?? bid.price + 0.01;
+            setCurrentPrice(bid.price);
+            setMinBid(min);
+            setBidPrice(min);
+
+            const label = `${bid.bidder} bid ${bid.price.toFixed(2)}`;
+            const next: BidEntry = { id: bid.bid_id, price: bid.price, label };
 
             setBids((prev) => {
-              // Dedupe latest bid
-              if (
-                prev.length > 0 &&
-                prev[0].price === next.price &&
-                prev[0].label === next.label
-              ) {
+              // Dedupe by server-assigned bid id
+              if (prev.some((b) => b.id === next.id)) {
                 return prev;
               }
               return [next, ...prev].slice(0, 20);
@@ -183,12 +310,12 @@ export function AuctionPage() {
       return;
     }
 
-    if (!id || !userId.trim()) {
-      setBidError("Username and Auction ID are required");
+    if (!id) {
+      setBidError("Auction ID is required");
       return;
     }
-    if (bidPrice <= currentPrice) {
-      setBidError("Bid must be higher than the current price");
+    if (bidPrice < minBid) {
+      setBidError(`Bid must be at least $${minBid.toFixed(2)}`);
       return;
     }
 
@@ -200,7 +327,6 @@ export function AuctionPage() {
         credentials: "include",
         body: JSON.stringify({
           Auctionid: id,
-          Userid: userId,
           Price: bidPrice,
         }),
       });
@@ -210,9 +336,13 @@ export function AuctionPage() {
           (data as { error?: string }).error ??
             "Bid was not accepted by the server"
         );
+        const { minBid: serverMin } = data as { minBid?: number };
+        if (serverMin) setMinBid(serverMin);
       } else {
+        const next = (data as { minNextBid?: number }).minNextBid ?? bidPrice + 0.01;
         setCurrentPrice(bidPrice);
-        setBidPrice(bidPrice);
+        setMinBid(next);
+        setBidPrice(next);
         // New bid entry will appear via WebSocket broadcast
       }
     } catch (err) {
@@ -223,6 +353,38 @@ export function AuctionPage() {
     }
   }
 
+  async function handleBuyNow() {
+    setBidError(null);
+
+    if (!isAuthenticated) {
+      setBidError("You must be logged in to buy now");
+      return;
+    }
+
+    try {
+      setBidLoading(true);
+      const res = await fetch(`${API_BASE}/api/auction/${id}/buy-now`, {
+        method: "POST",
+        credentials: "include",
+      });
+      const data = await res.json().catch(() => ({}));
+      if (data.success !== "1") {
+        setBidError(
+          (data as { error?: string }).error ?? "Buy-now was not accepted by the server"
+        );
+        if (res.status === 409) {
+          setAuction((prev) => (prev ? { ...prev, buyNowAvailable: false } : prev));
+        }
+      }
+      // The closed auction arrives via WebSocket broadcast
+    } catch (err) {
+      console.error("Failed to buy now:", err);
+      setBidError("Failed to buy now");
+    } finally {
+      setBidLoading(false);
+    }
+  }
+
   function increment(amount: number) {
     setBidPrice((prev) => Number((prev + amount).toFixed(2)));
   }
@@ -260,9 +422,61 @@ export function AuctionPage() {
           </span>
         </p>
         <p className="mb-3 text-sm text-slate-600">
-          Auction ends in:{" "}
+          {auction.status === "scheduled" ? "Auction starts in" : "Auction ends in"}:{" "}
           <span className="font-semibold text-slate-900">{timeLeft}</span>
         </p>
+        {auction.hasReserve && auction.status === "live" && (
+          <p className="mb-1 text-sm text-slate-600">
+            Reserve:{" "}
+            <span className="font-semibold text-slate-900">
+              {auction.reserveMet ? "met" : "not met"}
+            </span>
+          </p>
+        )}
+        {(auction.status === "live" || auction.status === "scheduled") && (
+          <p className="mb-1 text-sm text-slate-600">
+            Minimum next bid:{" "}
+            <span className="font-semibold text-slate-900">
+              ${minBid.toFixed(2)}
+            </span>
+          </p>
+        )}
+        {auction.buyNowPrice !== undefined && auction.status === "live" && (
+          <p className="mb-1 text-sm text-slate-600">
+            Buy now:{" "}
+            <span className="font-semibold text-slate-900">
+              ${auction.buyNowPrice.toFixed(2)}
+            </span>
+            {!auction.buyNowAvailable && " (no longer available)"}
+          </p>
+        )}
+        {auction.softCloseMinutes > 0 && auction.status !== "closed" && (
+          <p className="mb-3 text-xs text-slate-500">
+            Bids in the last {auction.softCloseMinutes} min extend the auction by{" "}
+            {auction.extensionMinutes} min
+          </p>
+        )}
+        {(auction.status === "closed" || auction.status === "settled") && (
+          <p className="mb-3 text-sm font-semibold text-slate-900">
+            {auction.winner && auction.closeReason === "buy_now"
+              ? `Bought now by ${auction.winner} for $${currentPrice.toFixed(2)}`
+              : auction.winner
+              ? `Won by ${auction.winner} for $${currentPrice.toFixed(2)}`
+              : auction.hasReserve
+              ? "Auction ended unsold, the reserve price was not met"
+              : "Auction ended without bids"}
+          </p>
+        )}
+        {auction.status === "cancelled" && (
+          <p className="mb-3 text-sm font-semibold text-red-700">
+            This auction was cancelled
+          </p>
+        )}
+        {auction.isHighestBidder && auction.status === "live" && (
+          <p className="mb-3 text-sm font-semibold text-green-700">
+            You are the highest bidder
+          </p>
+        )}
         {auction.imageUrl && (
           <div className="mt-3">
             <img
@@ -315,7 +529,7 @@ export function AuctionPage() {
               <Input
                 id="bidPrice"
                 type="number"
-                min={currentPrice ?? auction.startingPrice ?? 0}
+                min={minBid}
                 step="0.01"
                 value={bidPrice}
                 onChange={(e) => setBidPrice(Number(e.target.value) || 0)}
@@ -346,10 +560,24 @@ export function AuctionPage() {
             <Button
               type="submit"
               className="w-full bg-slate-900 text-white hover:bg-slate-800"
-              disabled={bidLoading || wsStatus !== "connected"}
+              disabled={
+                bidLoading || wsStatus !== "connected" || auction.status !== "live"
+              }
             >
               {bidLoading ? "Submitting bid..." : "Submit bid"}
             </Button>
+
+            {auction.buyNowPrice !== undefined && auction.buyNowAvailable && (
+              <Button
+                type="button"
+                variant="outline"
+                className="w-full"
+                onClick={handleBuyNow}
+                disabled={bidLoading || auction.status !== "live"}
+              >
+                Buy now for ${auction.buyNowPrice.toFixed(2)}
+              </Button>
+            )}
           </form>
         </div>
 
@@ -363,7 +591,7 @@ export function AuctionPage() {
             <ul className="max-h-96 space-y-1 overflow-y-auto text-xs">
               {bids.map((bid, i) => (
                 <li
-                  key={i}
+                  key={bid.id ?? i}
                   className="flex justify-between gap-2 text-slate-700"
                 >
                   <span className="truncate" title={bid.label}>
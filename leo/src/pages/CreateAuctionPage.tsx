@@ -5,13 +5,36 @@ import { Input } from "../components/ui/input";
 import { useAuth } from "../auth/AuthContext";
 import { API_BASE } from "../config/api";
 
+type IncrementType = "" | "fixed" | "percent" | "table";
+
+// Builds the increment rule sent to the API. Tables are written as "from:increment" pairs,
+// e.g. "0:1, 100:5, 1000:25".
+function incrementRule(type: IncrementType, value: string) {
+  if (type === "") return null;
+  if (type === "table") {
+    const bands = value
+      .split(",")
+      .map((pair) => pair.split(":").map((v) => Number(v.trim())))
+      .map(([from, increment]) => ({ from, increment }));
+    return { type, bands };
+  }
+  return { type, amount: Number(value) };
+}
+
 export function CreateAuctionPage() {
   const navigate = useNavigate();
   const { isAuthenticated } = useAuth();
   const [item, setItem] = useState("");
   const [startingPrice, setStartingPrice] = useState("");
+  const [reservePrice, setReservePrice] = useState("");
+  const [buyNowPrice, setBuyNowPrice] = useState("");
   const [image, setImage] = useState("");
+  const [startTime, setStartTime] = useState("");
   const [endTime, setEndTime] = useState("");
+  const [softCloseMinutes, setSoftCloseMinutes] = useState("");
+  const [extensionMinutes, setExtensionMinutes] = useState("");
+  const [incrementType, setIncrementType] = useState<IncrementType>("");
+  const [incrementValue, setIncrementValue] = useState("");
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState<string | null>(null);
 
@@ -40,8 +63,18 @@ export function CreateAuctionPage() {
         body: JSON.stringify({
           item,
           startingPrice: Number(startingPrice),
+          // hidden from bidders, the auction goes unsold below it
+          reservePrice: reservePrice ? Number(reservePrice) : null,
+          buyNowPrice: buyNowPrice ? Number(buyNowPrice) : null,
           image,
+          // empty means the auction opens right away
+          startTime,
           endTime,
+          // both empty disables soft close
+          softCloseMinutes: Number(softCloseMinutes) || 0,
+          extensionMinutes: Number(extensionMinutes) || 0,
+          // without it any higher bid is accepted
+          increment: incrementRule(incrementType, incrementValue),
         }),
       });
 
@@ -101,6 +134,79 @@ export function CreateAuctionPage() {
           />
         </div>
 
+        <div className="space-y-1">
+          <label
+            className="text-sm font-medium text-slate-800"
+            htmlFor="reservePrice"
+          >
+            Reserve Price (optional)
+          </label>
+          <Input
+            id="reservePrice"
+            type="number"
+            min="0"
+            step="0.01"
+            value={reservePrice}
+            onChange={(e) => setReservePrice(e.target.value)}
+            placeholder="Hidden minimum"
+          />
+        </div>
+
+        <div className="space-y-1">
+          <label
+            className="text-sm font-medium text-slate-800"
+            htmlFor="buyNowPrice"
+          >
+            Buy Now Price (optional)
+          </label>
+          <Input
+            id="buyNowPrice"
+            type="number"
+            min="0"
+            step="0.01"
+            value={buyNowPrice}
+            onChange={(e) => setBuyNowPrice(e.target.value)}
+            placeholder="Ends the auction at once"
+          />
+        </div>
+
+        <div className="space-y-1">
+          <label
+            className="text-sm font-medium text-slate-800"
+            htmlFor="incrementType"
+          >
+            Minimum Bid Increment (optional)
+          </label>
+          <div className="flex gap-2">
+            <select
+              id="incrementType"
+              className="h-9 rounded-md border border-slate-200 bg-white px-2 text-sm"
+              value={incrementType}
+              onChange={(e) => setIncrementType(e.target.value as IncrementType)}
+            >
+              <option value="">Any higher bid</option>
+              <option value="fixed">Fixed amount</option>
+              <option value="percent">Percent of price</option>
+              <option value="table">Table by price</option>
+            </select>
+            {incrementType !== "" && (
+              <Input
+                id="incrementValue"
+                type="text"
+                value={incrementValue}
+                onChange={(e) => setIncrementValue(e.target.value)}
+                placeholder={
+                  incrementType === "table"
+                    ? "0:1, 100:5, 1000:25"
+                    : incrementType === "percent"
+                    ? "5"
+                    : "1.00"
+                }
+              />
+            )}
+          </div>
+        </div>
+
         <div className="space-y-1">
           <label className="text-sm font-medium text-slate-800" htmlFor="image">
             Image URL
@@ -114,6 +220,21 @@ export function CreateAuctionPage() {
           />
         </div>
 
+        <div className="space-y-1">
+          <label
+            className="text-sm font-medium text-slate-800"
+            htmlFor="startTime"
+          >
+            Start Time (optional)
+          </label>
+          <Input
+            id="startTime"
+            type="datetime-local"
+            value={startTime}
+            onChange={(e) => setStartTime(e.target.value)}
+          />
+        </div>
+
         <div className="space-y-1">
           <label
             className="text-sm font-medium text-slate-800"
@@ -129,6 +250,43 @@ export function CreateAuctionPage() {
           />
         </div>
 
+        <div className="flex gap-2">
+          <div className="flex-1 space-y-1">
+            <label
+              className="text-sm font-medium text-slate-800"
+              htmlFor="softCloseMinutes"
+            >
+              Soft close window (min)
+            </label>
+            <Input
+              id="softCloseMinutes"
+              type="number"
+              min="0"
+              max="60"
+              value={softCloseMinutes}
+              onChange={(e) => setSoftCloseMinutes(e.target.value)}
+              placeholder="0"
+            />
+          </div>
+          <div className="flex-1 space-y-1">
+            <label
+              className="text-sm font-medium text-slate-800"
+              htmlFor="extensionMinutes"
+            >
+              Extend by (min)
+            </label>
+            <Input
+              id="extensionMinutes"
+              type="number"
+              min="0"
+              max="60"
+              value={extensionMinutes}
+              onChange={(e) => setExtensionMinutes(e.target.value)}
+              placeholder="0"
+            />
+          </div>
+        </div>
+
         {error && <p className="text-sm text-red-600">{error}</p>}
 
         <Button
@@ -0,0 +1,120 @@
+package admin
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"tauras/services"
+	t "tauras/types"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	maxLoadTestSessions = 1000
+	maxLoadTestTTL      = 24 * time.Hour
+)
+
+// HandleMintLoadTestSessions creates (or reuses) synthetic users and returns a short-lived
+// session cookie for each, so load tests go through the real auth path with distinct bidders.
+// The endpoint only exists when LOAD_TEST_ADMIN_TOKEN is set and the caller presents it as a bearer token.
+func HandleMintLoadTestSessions(c *gin.Context, ctx *t.AppContext) {
+	adminToken := os.Getenv("LOAD_TEST_ADMIN_TOKEN")
+	if adminToken == "" {
+		c.JSON(404, gin.H{"error": "Not found"})
+		return
+	}
+	given := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(given), []byte(adminToken)) != 1 {
+		c.JSON(401, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var body struct {
+		Count      int `json:"count"`
+		TTLSeconds int `json:"ttlSeconds"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.Count <= 0 {
+		c.JSON(400, gin.H{"error": "count is required"})
+		return
+	}
+	if body.Count > maxLoadTestSessions {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("count must be at most %d", maxLoadTestSessions)})
+		return
+	}
+	ttl := time.Duration(body.TTLSeconds) * time.Second
+	if ttl <= 0 {
+		ttl = 30 * time.Minute
+	}
+	if ttl > maxLoadTestTTL {
+		ttl = maxLoadTestTTL
+	}
+
+	out := make([]gin.H, 0, body.Count)
+	for i := 1; i <= body.Count; i++ {
+		email := fmt.Sprintf("loadtest-%d@loadtest.invalid", i)
+		userID, err := ensureLoadTestUser(ctx.DB, email)
+		if err != nil {
+			log.Printf("error creating load test user: %v", err)
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+		token, err := ctx.Session.CreateSession(userID, services.SessionOptions{
+			UserAgent: "loadtest",
+			IP:        c.ClientIP(),
+			TTL:       ttl,
+		})
+		if err != nil {
+			log.Printf("error creating load test session: %v", err)
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+		out = append(out, gin.H{
+			"userId": userID,
+			"email":  email,
+			"cookie": ctx.Session.CookieValue(token),
+		})
+	}
+
+	c.JSON(201, gin.H{
+		"expiresAt": time.Now().Add(ttl).UTC().Format(time.RFC3339),
+		"sessions":  out,
+	})
+}
+
+// ensureLoadTestUser returns the id of a synthetic user, creating it with an unusable password if needed
+func ensureLoadTestUser(db *sql.DB, email string) (uint64, error) {
+	var id uint64
+	err := db.QueryRow("SELECT id FROM users WHERE email = ? LIMIT 1", email).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return 0, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(hex.EncodeToString(secret)), bcrypt.MinCost)
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.Exec("INSERT INTO users (email, password_hash) VALUES (?, ?)", email, string(hash))
+	if err != nil {
+		return 0, err
+	}
+	newID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(newID), nil
+}
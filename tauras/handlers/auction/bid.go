@@ -2,35 +2,28 @@ package auction
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
+	"gemini/bidding"
+	"gemini/events"
 	"log"
 	"strconv"
+	"tauras/middleware"
+	"tauras/services"
 	t "tauras/types"
 	"time"
 
-	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/gin-gonic/gin"
 )
 
 type BidRequest struct {
-	Bidid string `json:"Bidid"` //gonna be generated by the system
 	Auctionid string `json:"Auctionid" binding:"required"` //mandatory
-	Userid string `json:"Userid" binding:"required"` //mandatory
+	Userid string `json:"Userid"` //optional, the bidder is always the session user and a mismatch is rejected
 	Price float64 `json:"Price" binding:"required"` //mandatory
-	Timestamp int64 `json:"Timestamp"` //gonna be generated by the system
 }
 
-func strPtr(s string) *string { return &s }
-
 func BidHandler(c *gin.Context, ctx *t.AppContext) {
-	s := ctx.Session.RequireSession(c)
+	user := middleware.CurrentUser(c)
 	db := ctx.DB
-	p := ctx.KafkaProducer
-
-	if s == nil {
-		return
-	}
 
 	var req BidRequest
 	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
@@ -38,8 +31,10 @@ func BidHandler(c *gin.Context, ctx *t.AppContext) {
 		return
 	}
 
-	req.Bidid = "bid-" + fmt.Sprint(req.Userid) + "-" + fmt.Sprint(req.Auctionid)
-	req.Timestamp = time.Now().Unix()
+	if req.Userid != "" && req.Userid != strconv.FormatUint(uint64(user.Id), 10) {
+		c.JSON(403, gin.H{"error": "Userid does not match the logged in user"})
+		return
+	}
 	fmt.Println("Received bid request: \n", req)
 
 	auctionID, err := strconv.ParseInt(req.Auctionid, 10, 64)
@@ -48,69 +43,144 @@ func BidHandler(c *gin.Context, ctx *t.AppContext) {
 		return
 	}
 
-	// Enforce auction end time (IST / Asia-Kolkata).
-	var endTime time.Time
-	if err := db.QueryRow("SELECT end_time FROM auctions WHERE id = ?", auctionID).Scan(&endTime); err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(404, gin.H{"error": "Auction not found"})
-			return
-		}
-		log.Printf("error selecting auction end_time: %v", err)
-		c.JSON(500, gin.H{"error": "Failed to validate auction end time"})
+	//do a atomic transaction to ensure we get the correct max bid and insert the new bid without race conditions
+	//the auction row is locked first so status, end time and price are checked against what the bid will update
+	tx , err := db.Begin()
+	if err != nil {
+		log.Printf("error starting transaction: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return;
+	}
+	var (
+		status string
+		startTime sql.NullTime
+		endTime time.Time
+		currentPrice float64
+		softClose int
+		extension int
+		rule sql.NullString
+	)
+	err = tx.QueryRow(
+		`SELECT status, start_time, end_time, COALESCE(current_price, starting_price), soft_close_seconds, extension_seconds, increment_rule
+		 FROM auctions WHERE id = ? FOR UPDATE`,
+		auctionID,
+	).Scan(&status, &startTime, &endTime, &currentPrice, &softClose, &extension, &rule)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		c.JSON(404, gin.H{"error": "Auction not found"})
 		return
 	}
-
-	loc, locErr := time.LoadLocation("Asia/Kolkata")
-	if locErr != nil {
-		log.Printf("error loading IST location: %v", locErr)
+	if err != nil {
+		tx.Rollback()
+		log.Printf("error selecting auction for bid: %v", err)
 		c.JSON(500, gin.H{"error": "Internal server error"})
 		return
 	}
 
-	nowIST := time.Now().In(loc)
-	endIST := endTime.In(loc)
-	if !nowIST.Before(endIST) {
+	// Enforce auction status and start/end time, the scheduler may not have opened or closed it yet
+	if status == services.StatusScheduled || (startTime.Valid && time.Now().Before(startTime.Time)) {
+		tx.Rollback()
+		resp := gin.H{"error": "Auction has not started yet"}
+		if startTime.Valid {
+			resp["startTime"] = startTime.Time.UTC().Format(time.RFC3339)
+		}
+		c.JSON(400, resp)
+		return
+	}
+	if status != services.StatusLive {
+		tx.Rollback()
+		c.JSON(400, gin.H{"error": "Auction is not open for bidding", "status": status})
+		return
+	}
+	if !time.Now().Before(endTime) {
+		tx.Rollback()
 		c.JSON(400, gin.H{"error": "Auction has already ended"})
 		return
 	}
-	//do a atomic transaction to ensure we get the correct max bid and insert the new bid without race conditions
-	//1st check if the new bid is higher than current_price from the auctions table and then update the bid if it else rollback
-	tx , err := db.Begin()
+	increments, err := bidding.ParseIncrementRule(rule.String)
 	if err != nil {
-		log.Printf("error starting transaction: %v", err)
-		c.JSON(500 , err)
+		tx.Rollback()
+		log.Printf("error parsing increment rule of auction %d: %v", auctionID, err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+	// the auction's increment rule sets how far above the current price a bid has to go
+	minBid := increments.MinNextBid(currentPrice)
+	if req.Price < minBid {
+		tx.Rollback();
+		c.JSON(400, gin.H{"error": "Bid was not high enough to update the current bid", "minBid": minBid})
 		return;
 	}
-	res , err := tx.Exec(
-		"Update auctions set current_price = ? where id = ? and current_price < ?",
-		req.Price, auctionID, req.Price,
-	);
+	nextMinBid := increments.MinNextBid(req.Price)
 
-	if err != nil {
+	// anti-sniping: a bid inside the soft close window pushes the end out, so others get a chance to answer
+	newEndTime := endTime
+	if softClose > 0 && time.Until(endTime) <= time.Duration(softClose)*time.Second {
+		newEndTime = endTime.Add(time.Duration(extension) * time.Second)
+	}
+
+	if _, err := tx.Exec("UPDATE auctions SET current_price = ?, end_time = ? WHERE id = ?", req.Price, newEndTime, auctionID); err != nil {
 		tx.Rollback()
 		log.Printf("error updating auction current price: %v", err)
-		c.JSON(500 , err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
 		return;
 	}
-	_ , err = tx.Exec(
+	bidRes , err := tx.Exec(
 		"INSERT INTO bids (auction_id, user_id, price) VALUES (?, ?, ?)",
-		auctionID, s.UserID, req.Price,
+		auctionID, user.Id, req.Price,
 	)
 	if err != nil {
 		tx.Rollback()
-		c.JSON(500 , err);
+		log.Printf("error inserting bid: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
 		return;
 	}
-	rowsaffected , err := res.RowsAffected();
+	// the bids primary key is the canonical bid id for the response, kafka and the websocket feed
+	bidID , err := bidRes.LastInsertId()
 	if err != nil {
 		tx.Rollback()
-		c.JSON(500, err)
+		log.Printf("error getting bid insert id: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return;
 	}
-	if rowsaffected == 0 {
-		tx.Rollback();
-		c.JSON(400, gin.H{"error": "Bid was not high enough to update the current bid"})
+
+	seq , err := services.NextAuctionSeq(tx, auctionID)
+	if err != nil {
+		tx.Rollback()
+		log.Printf("error assigning event sequence: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
 		return;
 	}
+
+	// the event goes into the outbox in the same transaction, the relay publishes it to kafka
+	env, err := events.New(strconv.FormatInt(auctionID, 10), seq, events.BidPlaced{
+		BidID: uint64(bidID),
+		Bidder: user.Alias(),
+		Price: req.Price,
+		MinNextBid: nextMinBid,
+	})
+	if err != nil {
+		tx.Rollback()
+		log.Printf("error building bid event: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return;
+	}
+	if err := services.EnqueueEvent(tx, env, middleware.TraceID(c)); err != nil {
+		tx.Rollback()
+		log.Printf("error writing bid event to outbox: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return;
+	}
+	if newEndTime != endTime {
+		extended := events.AuctionExtended{PreviousEndTime: endTime.UTC(), EndTime: newEndTime.UTC(), MinNextBid: nextMinBid}
+		if err := services.EnqueueAuctionEvent(tx, auctionID, extended, middleware.TraceID(c)); err != nil {
+			tx.Rollback()
+			log.Printf("error writing auction extended event to outbox: %v", err)
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return;
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		log.Printf("error committing transaction: %v", err)
 		c.JSON(500, err)
@@ -148,18 +218,5 @@ func BidHandler(c *gin.Context, ctx *t.AppContext) {
 	}
 	*/
 
-	msgbytes, err := json.Marshal(req)
-	if err != nil {
-		log.Println("Failed to marshal bid request: ", err)
-	}
-	if p != nil {
-		p.Produce(
-			&kafka.Message{
-				TopicPartition: kafka.TopicPartition{Topic: strPtr("bids"), Partition: kafka.PartitionAny},
-				Value:          msgbytes,
-			}, nil,
-		)
-	}
-
-	c.JSON(200, gin.H{"success": "1"})
+	c.JSON(200, gin.H{"success": "1", "bidId": bidID, "endTime": newEndTime.UTC().Format(time.RFC3339), "minNextBid": nextMinBid})
 }
@@ -0,0 +1,147 @@
+package auction
+
+import (
+	"database/sql"
+	"gemini/events"
+	"log"
+	"strconv"
+	"tauras/middleware"
+	"tauras/services"
+	t "tauras/types"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleBuyNow lets a bidder pay the buy-now price and win the auction on the spot.
+// It is only offered until a bid exceeds BUY_NOW_THRESHOLD of the buy-now price.
+func HandleBuyNow(c *gin.Context, ctx *t.AppContext) {
+	user := middleware.CurrentUser(c)
+	db := ctx.DB
+
+	auctionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid auction id"})
+		return
+	}
+
+	//the auction row is locked so no bid or close can slip in between the checks and the close
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("error starting transaction: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+	var (
+		status    string
+		sellerID  uint64
+		startTime sql.NullTime
+		endTime   time.Time
+		buyNow    sql.NullFloat64
+	)
+	err = tx.QueryRow(
+		"SELECT status, user_id, start_time, end_time, buy_now_price FROM auctions WHERE id = ? FOR UPDATE",
+		auctionID,
+	).Scan(&status, &sellerID, &startTime, &endTime, &buyNow)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		c.JSON(404, gin.H{"error": "Auction not found"})
+		return
+	}
+	if err != nil {
+		tx.Rollback()
+		log.Printf("error selecting auction for buy-now: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if !buyNow.Valid {
+		tx.Rollback()
+		c.JSON(400, gin.H{"error": "Auction has no buy-now price"})
+		return
+	}
+	if status != services.StatusLive || (startTime.Valid && time.Now().Before(startTime.Time)) || !time.Now().Before(endTime) {
+		tx.Rollback()
+		c.JSON(400, gin.H{"error": "Auction is not open for bidding", "status": status})
+		return
+	}
+	if sellerID == uint64(user.Id) {
+		tx.Rollback()
+		c.JSON(403, gin.H{"error": "Sellers cannot buy their own auction"})
+		return
+	}
+
+	// the seller's opening bid does not count against buy-now
+	var topBid float64
+	err = tx.QueryRow(
+		"SELECT COALESCE(MAX(price), 0) FROM bids WHERE auction_id = ? AND user_id <> ?",
+		auctionID, sellerID,
+	).Scan(&topBid)
+	if err != nil {
+		tx.Rollback()
+		log.Printf("error selecting top bid for buy-now: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !ctx.Auctions.BuyNowAvailable(buyNow.Float64, topBid) {
+		tx.Rollback()
+		c.JSON(409, gin.H{"error": "Buy-now is no longer available"})
+		return
+	}
+
+	// the purchase is recorded as a bid at the buy-now price, so bid history and the winner query stay consistent
+	price := buyNow.Float64
+	if _, err := tx.Exec("UPDATE auctions SET current_price = ? WHERE id = ?", price, auctionID); err != nil {
+		tx.Rollback()
+		log.Printf("error updating auction current price: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+	bidRes, err := tx.Exec(
+		"INSERT INTO bids (auction_id, user_id, price) VALUES (?, ?, ?)",
+		auctionID, user.Id, price,
+	)
+	if err != nil {
+		tx.Rollback()
+		log.Printf("error inserting buy-now bid: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+	bidID, err := bidRes.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		log.Printf("error getting bid insert id: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	alias := user.Alias()
+	placed := events.BidPlaced{BidID: uint64(bidID), Bidder: alias, Price: price}
+	if err := services.EnqueueAuctionEvent(tx, auctionID, placed, middleware.TraceID(c)); err != nil {
+		tx.Rollback()
+		log.Printf("error writing bid event to outbox: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+	winnerID := uint64(user.Id)
+	closed := events.AuctionClosed{
+		Winner:  &alias,
+		Price:   &price,
+		Outcome: events.OutcomeSold,
+		Reason:  events.ReasonBuyNow,
+	}
+	if err := services.CloseAuction(tx, auctionID, &winnerID, closed, middleware.TraceID(c)); err != nil {
+		tx.Rollback()
+		log.Printf("error closing auction for buy-now: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error committing transaction: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": "1", "bidId": bidID, "price": price, "status": services.StatusClosed})
+}
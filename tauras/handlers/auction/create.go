@@ -2,38 +2,50 @@ package auction
 
 import (
 	"fmt"
+	"gemini/bidding"
+	"gemini/events"
 	"log"
-	"tauras/models"
+	"strconv"
+	"tauras/middleware"
+	"tauras/services"
 	t "tauras/types"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
-func HandleCreateAuction(c *gin.Context , ctx *t.AppContext) {
-	s := ctx.Session.RequireSession(c)
-	db := ctx.Gdb;
+// maxSoftCloseMinutes bounds the soft close window and the extension
+const maxSoftCloseMinutes = 60
 
-	if s == nil {
-		return
-	}
+func HandleCreateAuction(c *gin.Context , ctx *t.AppContext) {
+	user := middleware.CurrentUser(c)
+	db := ctx.DB
 
 	var body struct {
 		Item          string   `json:"item"`
 		StartingPrice *float64 `json:"startingPrice"`
 		Image         *string  `json:"image"`
 		EndTime       string   `json:"endTime"`
+		StartTime     string   `json:"startTime"` // optional, the auction is live from creation without it
+		// optional soft close, a bid in the last SoftCloseMinutes extends the end by ExtensionMinutes
+		SoftCloseMinutes int `json:"softCloseMinutes"`
+		ExtensionMinutes int `json:"extensionMinutes"`
+		// optional hidden minimum, the auction goes unsold if bidding stops below it
+		ReservePrice *float64 `json:"reservePrice"`
+		// optional price a bidder can pay to win the auction outright
+		BuyNowPrice *float64 `json:"buyNowPrice"`
+		// optional minimum increment, fixed, percent or a table by price band
+		Increment bidding.IncrementRule `json:"increment"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil || body.Item == "" || body.StartingPrice == nil || body.EndTime == "" {
 		c.JSON(400, gin.H{"error": "Item, starting price, and end time are required"})
 		return
 	}
 
-	fmt.Println("##########################",s.UserID)
+	fmt.Println("##########################",user.Id)
 	fmt.Println("Received cte auction request: \n", body);
 
-	// Treat all end times as IST (Asia/Kolkata) local time.
+	// Treat all start and end times as IST (Asia/Kolkata) local time.
 	loc, err := time.LoadLocation("Asia/Kolkata")
 	if err != nil {
 		log.Printf("error loading IST location: %v", err)
@@ -47,49 +59,69 @@ func HandleCreateAuction(c *gin.Context , ctx *t.AppContext) {
 		c.JSON(400, gin.H{"error": "Invalid end time"})
 		return
 	}
+	if !endTime.After(time.Now()) {
+		c.JSON(400, gin.H{"error": "End time must be in the future"})
+		return
+	}
 
-	var image interface{}
-	if body.Image != nil && *body.Image != "" {
-		image = *body.Image
-	} else {
-		image = nil
-	}
-	//use a gorm transaction to ensure both auction and initial bid are created together
-	fmt.Println("##########################",s.UserID)
-	fmt.Println("heloooooooooooooooooooooooooooooooooo ")
-	auction := models.Auction{
-		Id:            0, // this will be set by the database
-		User_id: 	  s.UserID,
-		Item: 		body.Item,
-		Starting_price: *body.StartingPrice,
-		Image_url: image.(string),
-		End_time: endTime,
-		Current_price: *body.StartingPrice,
-	}
-
-	bid := models.Bid{
-		Id: 	  0, // this will be set by the database
-		Auction_id: auction.Id,
-		User_id:    s.UserID,
-		Price:     auction.Current_price,
-		Updated_at: time.Now(),
-	}
-
-	err = db.Transaction(func(tx *gorm.DB) error {
-
-		if err := tx.Create(&auction).Error; err != nil {
-			return err //rollback will be automatic if error is returned
+	if body.SoftCloseMinutes < 0 || body.ExtensionMinutes < 0 || body.SoftCloseMinutes > maxSoftCloseMinutes || body.ExtensionMinutes > maxSoftCloseMinutes {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Soft close and extension must be between 0 and %d minutes", maxSoftCloseMinutes)})
+		return
+	}
+	// the seller's opening bid is at the starting price, so a reserve at or below it would always be met
+	if body.ReservePrice != nil && *body.ReservePrice <= *body.StartingPrice {
+		c.JSON(400, gin.H{"error": "Reserve price must be above the starting price"})
+		return
+	}
+	if body.BuyNowPrice != nil && *body.BuyNowPrice <= *body.StartingPrice {
+		c.JSON(400, gin.H{"error": "Buy-now price must be above the starting price"})
+		return
+	}
+	// buying now always sells, so it must not undercut the seller's reserve
+	if body.BuyNowPrice != nil && body.ReservePrice != nil && *body.BuyNowPrice < *body.ReservePrice {
+		c.JSON(400, gin.H{"error": "Buy-now price must not be below the reserve price"})
+		return
+	}
+	if err := body.Increment.Validate(); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid increment: " + err.Error()})
+		return
+	}
+	incrementRule, err := body.Increment.Encode()
+	if err != nil {
+		log.Printf("error encoding increment rule: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+	if (body.SoftCloseMinutes == 0) != (body.ExtensionMinutes == 0) {
+		c.JSON(400, gin.H{"error": "Soft close and extension must be set together"})
+		return
+	}
+
+	// a start time in the future schedules the auction, the scheduler opens it then
+	status := services.StatusLive
+	var startTime *time.Time
+	if body.StartTime != "" {
+		st, err := time.ParseInLocation(layout, body.StartTime, loc)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid start time"})
+			return
+		}
+		if !endTime.After(st) {
+			c.JSON(400, gin.H{"error": "End time must be after start time"})
+			return
 		}
-		
-		if err := tx.Create(&bid).Error; err != nil {
-			return err //rollback will be automatic if error is returned
+		if st.After(time.Now()) {
+			status = services.StatusScheduled
+			startTime = &st
 		}
+	}
 
-		return nil
-	}) 
+	image := ""
+	if body.Image != nil {
+		image = *body.Image
+	}
 
-	//use a transaction to ensure both auction and initial bid are created together
-	/*
+	//use a transaction so the auction, its opening bid and the created event are written together
 	tx, err := db.Begin()
 	if err != nil {
 		log.Printf("error starting transaction: %v", err)
@@ -98,8 +130,10 @@ func HandleCreateAuction(c *gin.Context , ctx *t.AppContext) {
 	}
 
 	res, err := tx.Exec(
-		"INSERT INTO auctions (user_id, item, starting_price, image_url, end_time , current_price) VALUES (?, ?, ?, ?, ? , ?)",
-		s.UserID, body.Item, *body.StartingPrice, image, endTime, *body.StartingPrice,
+		`INSERT INTO auctions (user_id, item, starting_price, image_url, start_time, end_time , current_price, status, soft_close_seconds, extension_seconds, reserve_price, buy_now_price, increment_rule)
+		 VALUES (?, ?, ?, ?, ?, ? , ?, ?, ?, ?, ?, ?, ?)`,
+		user.Id, body.Item, *body.StartingPrice, image, startTime, endTime, *body.StartingPrice, status,
+		body.SoftCloseMinutes*60, body.ExtensionMinutes*60, body.ReservePrice, body.BuyNowPrice, incrementRule,
 	)
 	if err != nil {
 		tx.Rollback()
@@ -117,10 +151,11 @@ func HandleCreateAuction(c *gin.Context , ctx *t.AppContext) {
 	}
 
 	_, err = tx.Exec(
-		"INSERT INTO bids (auction_id, user_id, price) VALUES (?, ?, ?)",
+		"INSERT INTO bids (auction_id, user_id, price, updated_at) VALUES (?, ?, ?, ?)",
 		auctionID,
-		s.UserID,
+		user.Id,
 		*body.StartingPrice,
+		time.Now(),
 	)
 	if err != nil {
 		tx.Rollback()
@@ -129,13 +164,45 @@ func HandleCreateAuction(c *gin.Context , ctx *t.AppContext) {
 		return
 	}
 
+	seq, err := services.NextAuctionSeq(tx, auctionID)
+	if err != nil {
+		tx.Rollback()
+		log.Printf("error assigning event sequence: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+	created := events.AuctionCreated{
+		Item:          body.Item,
+		Seller:        user.Alias(),
+		StartingPrice: *body.StartingPrice,
+		ImageURL:      image,
+		EndTime:       endTime.UTC(),
+		MinNextBid:    body.Increment.MinNextBid(*body.StartingPrice),
+	}
+	if startTime != nil {
+		st := startTime.UTC()
+		created.StartTime = &st
+	}
+	env, err := events.New(strconv.FormatInt(auctionID, 10), seq, created)
+	if err != nil {
+		tx.Rollback()
+		log.Printf("error building auction created event: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+	if err := services.EnqueueEvent(tx, env, middleware.TraceID(c)); err != nil {
+		tx.Rollback()
+		log.Printf("error writing auction created event to outbox: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+
 	if err := tx.Commit(); err != nil {
 		log.Printf("error committing transaction: %v", err)
 		c.JSON(500, gin.H{"error": "Internal server error"})
 		return
 	}
-	*/
 
-	c.JSON(201, gin.H{"auctionId": auction.Id})
+	c.JSON(201, gin.H{"auctionId": auctionID, "status": status})
 
 }
\ No newline at end of file
@@ -2,7 +2,11 @@ package auction
 
 import (
 	"database/sql"
+	"gemini/bidding"
+	"gemini/events"
 	"log"
+	"tauras/middleware"
+	"tauras/services"
 	t "tauras/types"
 	"time"
 
@@ -17,16 +21,30 @@ func HandleGetAuction(c *gin.Context , ctx *t.AppContext) {
 		startingPrice float64
 		currentPrice  float64
 		imageURL      sql.NullString
+		startTime     sql.NullTime
 		endTime       time.Time
+		status        string
+		winner        sql.NullString
+		winnerID      sql.NullInt64
+		softClose     int
+		extension     int
+		reserve       sql.NullFloat64
+		outcome       string
+		buyNow        sql.NullFloat64
+		topBid        float64
+		rule          sql.NullString
 	)
 
 	err := db.QueryRow(
 		`SELECT a.id, a.item, a.starting_price,
 		 COALESCE((SELECT MAX(b.price) FROM bids b WHERE b.auction_id = a.id), a.starting_price),
-		 a.image_url, a.end_time
-		 FROM auctions a WHERE a.id = ?`,
+		 a.image_url, a.start_time, a.end_time, a.status, a.winner_id, w.display_name,
+		 a.soft_close_seconds, a.extension_seconds, a.reserve_price, a.outcome, a.buy_now_price,
+		 COALESCE((SELECT MAX(b.price) FROM bids b WHERE b.auction_id = a.id AND b.user_id <> a.user_id), 0),
+		 a.increment_rule
+		 FROM auctions a LEFT JOIN users w ON w.id = a.winner_id WHERE a.id = ?`,
 		id,
-	).Scan(&auctionID, &item, &startingPrice, &currentPrice, &imageURL, &endTime)
+	).Scan(&auctionID, &item, &startingPrice, &currentPrice, &imageURL, &startTime, &endTime, &status, &winnerID, &winner, &softClose, &extension, &reserve, &outcome, &buyNow, &topBid, &rule)
 	if err == sql.ErrNoRows {
 		c.JSON(404, gin.H{"error": "Auction not found"})
 		return
@@ -37,17 +55,80 @@ func HandleGetAuction(c *gin.Context , ctx *t.AppContext) {
 		return
 	}
 
+	increments, err := bidding.ParseIncrementRule(rule.String)
+	if err != nil {
+		log.Printf("error parsing increment rule of auction %d: %v", auctionID, err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+
 	var img *string
 	if imageURL.Valid {
 		img = &imageURL.String
 	}
 
-	c.JSON(200, gin.H{
+	resp := gin.H{
 		"id":            auctionID,
 		"item":          item,
 		"startingPrice": startingPrice,
 		"currentPrice":  currentPrice,
 		"imageUrl":      img,
 		"endTime":       endTime.UTC().Format(time.RFC3339),
-	})
+		"status":        status,
+		// bids in the last softCloseMinutes push endTime out by extensionMinutes, both 0 without soft close
+		"softCloseMinutes": softClose / 60,
+		"extensionMinutes": extension / 60,
+		// the reserve itself stays secret, viewers only learn whether bidding has reached it
+		"hasReserve": reserve.Valid,
+	}
+	if reserve.Valid {
+		resp["reserveMet"] = currentPrice >= reserve.Float64
+	}
+	// buy-now is public, it is withdrawn once bidding gets close to it
+	if buyNow.Valid {
+		resp["buyNowPrice"] = buyNow.Float64
+		resp["buyNowAvailable"] = status == services.StatusLive && ctx.Auctions.BuyNowAvailable(buyNow.Float64, topBid)
+	}
+	// the increment rule is public, minNextBid is what a bid has to reach while bidding is open
+	if increments.Type != "" {
+		resp["increment"] = increments
+	}
+	if status == services.StatusLive || status == services.StatusScheduled {
+		resp["minNextBid"] = increments.MinNextBid(currentPrice)
+	}
+	if outcome != "" {
+		resp["outcome"] = outcome
+	}
+
+	// scheduled auctions report when they open and how long until then
+	if startTime.Valid {
+		resp["startTime"] = startTime.Time.UTC().Format(time.RFC3339)
+		if status == services.StatusScheduled {
+			resp["startsInSeconds"] = max(0, int64(time.Until(startTime.Time).Seconds()))
+		}
+	}
+
+	// the winner is public once the auction is over, by alias only
+	if winnerID.Valid {
+		resp["winner"] = events.Alias(uint64(winnerID.Int64), winner.String)
+	}
+
+	// logged in viewers also learn whether they currently hold the top bid
+	if user := middleware.CurrentUser(c); user != nil {
+		var topBidder uint64
+		err := db.QueryRow(
+			`SELECT b.user_id FROM bids b JOIN auctions a ON a.id = b.auction_id
+			 WHERE b.auction_id = ? AND b.user_id <> a.user_id
+			 ORDER BY b.price DESC, b.id ASC LIMIT 1`,
+			auctionID,
+		).Scan(&topBidder)
+		if err != nil && err != sql.ErrNoRows {
+			log.Printf("error selecting highest bidder: %v", err)
+			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+		resp["isHighestBidder"] = err == nil && topBidder == uint64(user.Id)
+	}
+
+	c.JSON(200, resp)
 }
\ No newline at end of file
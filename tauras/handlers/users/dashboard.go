@@ -1,18 +1,17 @@
 package users
 
 import (
+	"tauras/middleware"
 	t "tauras/types"
 
 	"github.com/gin-gonic/gin"
 )
 
 func HandleDashboard(c *gin.Context , ctx *t.AppContext ){
-	s := ctx.Session.RequireSession(c)
-	if s == nil {
-		return
-	}
+	user := middleware.CurrentUser(c)
 	c.JSON(200, gin.H{
 		"message": "Dashboard data placeholder",
-		"userId":  s.UserID,
+		"userId":  user.Id,
+		"email":   user.Email,
 	})
 }
\ No newline at end of file
@@ -3,6 +3,7 @@ package users
 import (
 	"database/sql"
 	"log"
+	"tauras/services"
 	t "tauras/types"
 
 	"github.com/gin-gonic/gin"
@@ -41,7 +42,15 @@ func HandleLogin(c *gin.Context , ctx *t.AppContext) {
 		return
 	}
 
-	token := ctx.Session.CreateSession(userID)
+	token, err := ctx.Session.CreateSession(userID, services.SessionOptions{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	})
+	if err != nil {
+		log.Printf("error creating session: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
 	ctx.Session.SetSessionCookie(c, token)
 	c.JSON(200, gin.H{"id": userID, "email": body.Email})
 }
\ No newline at end of file
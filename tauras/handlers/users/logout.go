@@ -0,0 +1,33 @@
+package users
+
+import (
+	"log"
+	"tauras/middleware"
+	t "tauras/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleLogout ends the session the request was made with
+func HandleLogout(c *gin.Context, ctx *t.AppContext) {
+	s := middleware.CurrentSession(c)
+	if err := ctx.Session.RevokeSession(s.ID); err != nil {
+		log.Printf("error revoking session: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+	ctx.Session.ClearSessionCookie(c)
+	c.JSON(200, gin.H{"success": "1"})
+}
+
+// HandleLogoutAll ends every session of the current user, including this one
+func HandleLogoutAll(c *gin.Context, ctx *t.AppContext) {
+	s := middleware.CurrentSession(c)
+	if err := ctx.Session.RevokeAllSessions(s.UserID); err != nil {
+		log.Printf("error revoking sessions: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+	ctx.Session.ClearSessionCookie(c)
+	c.JSON(200, gin.H{"success": "1"})
+}
@@ -2,22 +2,42 @@ package users
 
 import (
 	"database/sql"
+	"errors"
 	"log"
+	"strings"
+	"tauras/services"
 	t "tauras/types"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-sql-driver/mysql"
 	"golang.org/x/crypto/bcrypt"
 )
+
+// aliasAttempts bounds how often a generated alias is redrawn after colliding with a taken name
+const aliasAttempts = 3
+
 func HandleRegister(c *gin.Context , ctx *t.AppContext) {
 	authDB := ctx.DB
 	var body struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email       string `json:"email"`
+		Password    string `json:"password"`
+		DisplayName string `json:"displayName"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil || body.Email == "" || body.Password == "" {
 		c.JSON(400, gin.H{"error": "Email and password are required"})
 		return
 	}
+	body.DisplayName = strings.TrimSpace(body.DisplayName)
+	if len(body.DisplayName) > 64 {
+		c.JSON(400, gin.H{"error": "Display name must be at most 64 characters"})
+		return
+	}
+	// generated aliases and pseudonyms of users without a display name live under this prefix
+	if strings.HasPrefix(strings.ToLower(body.DisplayName), aliasPrefix) {
+		c.JSON(400, gin.H{"error": "Display name must not start with " + aliasPrefix})
+		return
+	}
+	generated := body.DisplayName == ""
 
 	var existingID int64
 	err := authDB.QueryRow("SELECT id FROM users WHERE email = ? LIMIT 1", body.Email).Scan(&existingID)
@@ -38,7 +58,29 @@ func HandleRegister(c *gin.Context , ctx *t.AppContext) {
 		return
 	}
 
-	res, err := authDB.Exec("INSERT INTO users (email, password_hash) VALUES (?, ?)", body.Email, string(passwordHash))
+	var res sql.Result
+	for attempt := 1; ; attempt++ {
+		if generated {
+			body.DisplayName, err = randomAlias()
+			if err != nil {
+				log.Printf("error generating alias: %v", err)
+				c.JSON(500, gin.H{"error": "Internal server error"})
+				return
+			}
+		}
+		res, err = authDB.Exec("INSERT INTO users (email, password_hash, display_name) VALUES (?, ?, ?)", body.Email, string(passwordHash), body.DisplayName)
+		var myErr *mysql.MySQLError
+		if err == nil || !errors.As(err, &myErr) || myErr.Number != 1062 { // 1062 = duplicate entry
+			break
+		}
+		if !generated {
+			c.JSON(409, gin.H{"error": "Display name is already taken"})
+			return
+		}
+		if attempt == aliasAttempts {
+			break
+		}
+	}
 	if err != nil {
 		log.Printf("error inserting user: %v", err)
 		c.JSON(500, gin.H{"error": "Internal server error"})
@@ -52,7 +94,15 @@ func HandleRegister(c *gin.Context , ctx *t.AppContext) {
 		return
 	}
 
-	token := ctx.Session.CreateSession(uint64(userID))
+	token, err := ctx.Session.CreateSession(uint64(userID), services.SessionOptions{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	})
+	if err != nil {
+		log.Printf("error creating session: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
 	ctx.Session.SetSessionCookie(c, token)
-	c.JSON(201, gin.H{"id": userID, "email": body.Email})
+	c.JSON(201, gin.H{"id": userID, "email": body.Email, "displayName": body.DisplayName})
 }
@@ -0,0 +1,75 @@
+package users
+
+import (
+	"log"
+	"tauras/middleware"
+	"strconv"
+	t "tauras/types"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleListSessions returns the active sessions of the current user
+func HandleListSessions(c *gin.Context, ctx *t.AppContext) {
+	s := middleware.CurrentSession(c)
+	list, err := ctx.Session.ListSessions(s.UserID)
+	if err != nil {
+		log.Printf("error listing sessions: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	out := make([]gin.H, 0, len(list))
+	for _, sess := range list {
+		out = append(out, gin.H{
+			"id":         sess.ID,
+			"userAgent":  sess.UserAgent,
+			"ip":         sess.IP,
+			"createdAt":  sess.CreatedAt.UTC().Format(time.RFC3339),
+			"lastSeenAt": sess.LastSeenAt.UTC().Format(time.RFC3339),
+			"expiresAt":  sess.ExpiresAt.UTC().Format(time.RFC3339),
+			"current":    sess.ID == s.ID,
+		})
+	}
+	c.JSON(200, gin.H{"sessions": out})
+}
+
+// HandleRevokeSession ends one of the current user's sessions by id
+func HandleRevokeSession(c *gin.Context, ctx *t.AppContext) {
+	s := middleware.CurrentSession(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid session id"})
+		return
+	}
+
+	// only allow revoking sessions that belong to the caller
+	list, err := ctx.Session.ListSessions(s.UserID)
+	if err != nil {
+		log.Printf("error listing sessions: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+	found := false
+	for _, sess := range list {
+		if sess.ID == id {
+			found = true
+			break
+		}
+	}
+	if !found {
+		c.JSON(404, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if err := ctx.Session.RevokeSession(id); err != nil {
+		log.Printf("error revoking session: %v", err)
+		c.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+	if id == s.ID {
+		ctx.Session.ClearSessionCookie(c)
+	}
+	c.JSON(200, gin.H{"success": "1"})
+}
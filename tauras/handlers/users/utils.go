@@ -1 +1,19 @@
 package users
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// aliasPrefix marks generated names, users cannot pick a display name starting with it
+const aliasPrefix = "bidder-"
+
+// randomAlias is the display name given to users who did not pick one.
+// 64 bits keep collisions rare, the unique index on display_name catches the rest.
+func randomAlias() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return aliasPrefix + hex.EncodeToString(b), nil
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"gemini/events"
 	"log"
 	"os"
+	"tauras/middleware"
 	"tauras/models"
 	"tauras/routes"
 	"tauras/services"
@@ -15,7 +17,6 @@ import (
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 
-	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	_ "github.com/go-sql-driver/mysql"
@@ -31,11 +32,23 @@ func migrate() ( *gorm.DB , error) {
 	if err != nil {
 		return nil , err
 	}
+	// sessions used to store the raw token, those rows can no longer be verified
+	if gormDb.Migrator().HasColumn(&models.Session{}, "token") {
+		if err := gormDb.Exec("DELETE FROM sessions").Error; err != nil {
+			return nil, err
+		}
+		if err := gormDb.Migrator().DropColumn(&models.Session{}, "token"); err != nil {
+			return nil, err
+		}
+	}
 	//auto migrate the auction
 	err = gormDb.AutoMigrate(
 		&models.Auction{},
 		&models.Bid{},
 		&models.User{},
+		&models.Session{},
+		&models.IdempotencyKey{},
+		&models.OutboxMessage{},
 	)
 	if err != nil {
 		return nil , err;
@@ -68,34 +81,83 @@ func setupDb() (*sql.DB, error) {
 }
 
 
-func setupKafkaProducer() (*kafka.Producer, error) {
-	if err := godotenv.Load(); err != nil {
-		log.Println(".env not found, using system environment variables")
+// setupPublisher picks the event publisher from EVENT_PUBLISHER (kafka, memory or log)
+func setupPublisher() (services.EventPublisher, error) {
+	switch getEnv("EVENT_PUBLISHER", "kafka") {
+	case "memory":
+		log.Println("Using in-memory event publisher, events are logged by an in-process subscriber")
+		p := services.NewChannelPublisher(1024)
+		go services.LogMessages(p.Subscribe())
+		return p, nil
+	case "log":
+		log.Println("Using logging event publisher, events will not leave this process")
+		return services.LogPublisher{}, nil
+	default:
+		brokers := getEnv("KAFKA_BROKERS", "kafka:9092")
+		fmt.Println("Setting up Kafka producer with brokers:", brokers)
+		return services.NewKafkaPublisher(brokers)
+	}
+}
+
+// eventContentType reads the wire encoding of published events from EVENT_ENCODING (json or protobuf)
+func eventContentType() string {
+	switch getEnv("EVENT_ENCODING", "json") {
+	case "protobuf":
+		return events.ContentTypeProtobuf
+	default:
+		return events.ContentTypeJSON
 	}
+}
 
-	// kafka_broker := os.Getenv("KAFKA_BROKERS")
-	kafka_broker := "kafka:9092";
-	fmt.Println("Setting up Kafka producer with brokers:", kafka_broker)
-	if kafka_broker == "" {
-		return nil, fmt.Errorf("kafka brokers are not set in KAFKA_BROKERS")
+// setupSessions picks the session store from SESSION_STORE (mysql or memory)
+func setupSessions(db *sql.DB) services.SessionService {
+	cfg := services.SessionConfigFromEnv()
+	switch getEnv("SESSION_STORE", "mysql") {
+	case "memory":
+		log.Println("Using in-memory session store, sessions will not survive a restart")
+		return services.NewMemorySessionService(cfg)
+	default:
+		return services.NewMySQLSessionService(db, cfg)
 	}
-	p , err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": kafka_broker})
+}
+
+// setupIdempotency picks the idempotency key store from IDEMPOTENCY_STORE (mysql or memory)
+func setupIdempotency(db *sql.DB) services.IdempotencyStore {
+	ttl, err := time.ParseDuration(getEnv("IDEMPOTENCY_TTL", "24h"))
 	if err != nil {
-		return nil , err;
+		log.Printf("invalid IDEMPOTENCY_TTL, using 24h: %v", err)
+		ttl = 24 * time.Hour
 	}
-	go func() {
-		for e := range p.Events() {
-			switch ev := e.(type) {
-			case *kafka.Message:
-				if ev.TopicPartition.Error != nil {
-					fmt.Println("Delivery failed:", ev.TopicPartition)
-				} else {
-					fmt.Println("Delivered to", ev.TopicPartition)
-				}
-			}
+	// a request still in progress after the lease is assumed dead, its key can be reserved again
+	lease, err := time.ParseDuration(getEnv("IDEMPOTENCY_LEASE", "1m"))
+	if err != nil || lease <= 0 {
+		log.Printf("invalid IDEMPOTENCY_LEASE, using 1m: %v", err)
+		lease = time.Minute
+	}
+	switch getEnv("IDEMPOTENCY_STORE", "mysql") {
+	case "memory":
+		return services.NewMemoryIdempotencyStore(ttl, lease)
+	default:
+		return services.NewMySQLIdempotencyStore(db, ttl, lease)
+	}
+}
+
+type purger interface {
+	PurgeExpired() (int64, error)
+}
+
+// purgeExpired periodically removes expired rows from a store
+func purgeExpired(name string, p purger, every time.Duration) {
+	for range time.Tick(every) {
+		n, err := p.PurgeExpired()
+		if err != nil {
+			log.Printf("error purging %s: %v", name, err)
+			continue
 		}
-	}()
-	return p , nil;
+		if n > 0 {
+			log.Printf("Purged %d expired %s", n, name)
+		}
+	}
 }
 
 func getEnv(key, def string) string {
@@ -110,7 +172,7 @@ func main(){
 	//Run Migrations	
 	gdb , err := migrate();
 	if err != nil {
-		log.Fatalf("Failed to run migrations: ", err)
+		log.Fatalf("Failed to run migrations: %v", err)
 		return;
 	}
 
@@ -124,21 +186,44 @@ func main(){
 
 	log.Println("Successfully connected to database");
 
-	//set up the kafka producer
-	p , err := setupKafkaProducer();
+	//set up the event publisher
+	p , err := setupPublisher();
 
 	if err != nil {
-		fmt.Println("Failed to set up Kafka producer: ", err);
+		fmt.Println("Failed to set up event publisher: ", err);
 		panic(err);
 	}
 
 	defer p.Close();
-	log.Println("Successfully set up Kafka producer");
-	
+	log.Println("Successfully set up event publisher");
+
+	sessions := setupSessions(db)
+	go purgeExpired("sessions", sessions, time.Hour)
+
+	idempotency := setupIdempotency(db)
+	go purgeExpired("idempotency keys", idempotency, time.Hour)
+
+	//relay events written to the outbox by the handlers to the publisher
+	relay := services.NewOutboxRelay(db, p)
+	relay.ContentType = eventContentType()
+	go relay.Run(context.Background())
+	go purgeExpired("outbox messages", relay, time.Hour)
+
+	//open and close auctions at their start and end times
+	scheduler := services.NewAuctionScheduler(db)
+	if interval, err := time.ParseDuration(getEnv("AUCTION_SCHEDULER_INTERVAL", "1s")); err == nil && interval > 0 {
+		scheduler.Interval = interval
+	} else {
+		log.Printf("invalid AUCTION_SCHEDULER_INTERVAL, using %s", scheduler.Interval)
+	}
+	go scheduler.Run(context.Background())
+
 	ctx := &types.AppContext{
 		DB: db , //the db connection
-		Session: &services.SessionService{}, //the session service
-		KafkaProducer: p, //the kafka producer
+		Session: sessions, //the session service
+		Idempotency: idempotency, //stored responses for retried requests
+		Events: p, //the event publisher
+		Auctions: services.AuctionConfigFromEnv(), //marketplace rules like the buy-now threshold
 		Gdb : gdb, //the gorm db for migrations and other operations
 	};
 
@@ -148,12 +233,14 @@ func main(){
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://leo:5173" , "http://localhost:5173"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Idempotency-Key", "X-Request-Id"},
+		ExposeHeaders:    []string{"Content-Length", "Idempotent-Replayed", "X-Request-Id"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	r.Use(middleware.Trace());
+
 	routes.SetupRoutes(r , ctx);
 	r.Run(":3000");
 }
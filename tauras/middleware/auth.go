@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"tauras/models"
+	"tauras/services"
+	t "tauras/types"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	sessionKey = "auth.session"
+	userKey    = "auth.user"
+)
+
+// RequireAuth resolves the session cookie and the user once per request, aborting with 401 when either is missing
+func RequireAuth(ctx *t.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !authenticate(c, ctx) {
+			c.AbortWithStatusJSON(401, gin.H{"error": "Unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// OptionalAuth does the same lookup as RequireAuth but lets anonymous requests through
+func OptionalAuth(ctx *t.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticate(c, ctx)
+		c.Next()
+	}
+}
+
+// CurrentUser returns the authenticated user, or nil on anonymous requests
+func CurrentUser(c *gin.Context) *models.User {
+	if v, ok := c.Get(userKey); ok {
+		return v.(*models.User)
+	}
+	return nil
+}
+
+// CurrentSession returns the session the request was made with, or nil on anonymous requests
+func CurrentSession(c *gin.Context) *services.Session {
+	if v, ok := c.Get(sessionKey); ok {
+		return v.(*services.Session)
+	}
+	return nil
+}
+
+func authenticate(c *gin.Context, ctx *t.AppContext) bool {
+	s := ctx.Session.ParseSessionCookie(c)
+	if s == nil {
+		return false
+	}
+	var user models.User
+	if err := ctx.Gdb.First(&user, s.UserID).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("error loading session user: %v", err)
+		}
+		return false
+	}
+	c.Set(sessionKey, s)
+	c.Set(userKey, &user)
+	return true
+}
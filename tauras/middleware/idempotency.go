@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	t "tauras/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// Idempotency replays the stored response when a request is retried with the same Idempotency-Key header,
+// instead of running the handler again. Must run after RequireAuth, keys are scoped per user.
+func Idempotency(ctx *t.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(400, gin.H{"error": "Idempotency-Key is too long"})
+			return
+		}
+		user := CurrentUser(c)
+		if user == nil {
+			c.AbortWithStatusJSON(401, gin.H{"error": "Unauthorized"})
+			return
+		}
+		userID := uint64(user.Id)
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(400, gin.H{"error": "Failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(append([]byte(c.Request.Method+" "+c.Request.URL.Path+"\n"), body...))
+		hash := hex.EncodeToString(sum[:])
+
+		existing, err := ctx.Idempotency.Begin(userID, key, hash)
+		if err != nil {
+			log.Printf("error reserving idempotency key: %v", err)
+			c.AbortWithStatusJSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+		if existing != nil {
+			switch {
+			case existing.RequestHash != hash:
+				c.AbortWithStatusJSON(422, gin.H{"error": "Idempotency-Key was already used for a different request"})
+			case existing.StatusCode == 0:
+				c.AbortWithStatusJSON(409, gin.H{"error": "A request with this Idempotency-Key is still in progress"})
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(existing.StatusCode, "application/json; charset=utf-8", existing.Response)
+				c.Abort()
+			}
+			return
+		}
+
+		// server errors are not remembered so the client can retry them. The release is deferred so it
+		// also runs when the handler panics; if the process dies instead, the reservation's lease runs out.
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := ctx.Idempotency.Release(userID, key); err != nil {
+				log.Printf("error releasing idempotency key: %v", err)
+			}
+		}()
+
+		rec := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+		c.Next()
+
+		status := rec.Status()
+		if status >= 500 {
+			return
+		}
+		// the request went through, so the key is never released from here on; if storing the
+		// response fails the reservation expires with its lease instead
+		completed = true
+		if err := ctx.Idempotency.Complete(userID, key, status, rec.body.Bytes()); err != nil {
+			log.Printf("error storing idempotent response: %v", err)
+		}
+	}
+}
+
+// responseRecorder keeps a copy of the response body while writing it through
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
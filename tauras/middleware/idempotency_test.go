@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"tauras/models"
+	"tauras/services"
+	t "tauras/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// idempotencyRouter serves POST /bids behind Idempotency for user 1, answering with handler
+func idempotencyRouter(store services.IdempotencyStore, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.RecoveryWithWriter(io.Discard), func(c *gin.Context) {
+		c.Set(userKey, &models.User{Id: 1})
+	}, Idempotency(&t.AppContext{Idempotency: store}))
+	r.POST("/bids", handler)
+	return r
+}
+
+func post(r *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/bids", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", key)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	calls := 0
+	r := idempotencyRouter(services.NewMemoryIdempotencyStore(time.Hour, time.Minute), func(c *gin.Context) {
+		calls++
+		c.JSON(201, gin.H{"id": calls})
+	})
+
+	first := post(r, "k", `{"price":10}`)
+	second := post(r, "k", `{"price":10}`)
+	if calls != 1 {
+		t.Fatalf("handler ran %d times, want once", calls)
+	}
+	if second.Code != 201 || second.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %s, want %d %s", second.Code, second.Body, first.Code, first.Body)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("Idempotent-Replayed header set on the wrong response")
+	}
+
+	// another key runs the handler again
+	if w := post(r, "other", `{"price":10}`); w.Code != 201 || calls != 2 {
+		t.Errorf("new key got %d after %d calls", w.Code, calls)
+	}
+}
+
+func TestIdempotencyRejectsDifferentRequest(t *testing.T) {
+	r := idempotencyRouter(services.NewMemoryIdempotencyStore(time.Hour, time.Minute), func(c *gin.Context) {
+		c.JSON(201, gin.H{})
+	})
+	post(r, "k", `{"price":10}`)
+	if w := post(r, "k", `{"price":11}`); w.Code != 422 {
+		t.Errorf("reused key with another body got %d, want 422", w.Code)
+	}
+}
+
+func TestIdempotencyConflictsWhileInProgress(t *testing.T) {
+	store := services.NewMemoryIdempotencyStore(time.Hour, time.Minute)
+	started, finish := make(chan struct{}), make(chan struct{})
+	r := idempotencyRouter(store, func(c *gin.Context) {
+		close(started)
+		<-finish
+		c.JSON(201, gin.H{})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- post(r, "k", `{"price":10}`) }()
+	<-started
+	if w := post(r, "k", `{"price":10}`); w.Code != 409 {
+		t.Errorf("retry during the first request got %d, want 409", w.Code)
+	}
+	close(finish)
+	if w := <-done; w.Code != 201 {
+		t.Errorf("first request got %d", w.Code)
+	}
+}
+
+func TestIdempotencyReleasesOnServerError(t *testing.T) {
+	calls := 0
+	r := idempotencyRouter(services.NewMemoryIdempotencyStore(time.Hour, time.Minute), func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			c.JSON(503, gin.H{"error": "try again"})
+			return
+		}
+		c.JSON(201, gin.H{})
+	})
+	post(r, "k", `{"price":10}`)
+	if w := post(r, "k", `{"price":10}`); w.Code != 201 || calls != 2 {
+		t.Errorf("retry after a 5xx got %d after %d calls, want the handler to run again", w.Code, calls)
+	}
+}
+
+func TestIdempotencyReleasesOnPanic(t *testing.T) {
+	calls := 0
+	r := idempotencyRouter(services.NewMemoryIdempotencyStore(time.Hour, time.Minute), func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			panic("handler failed")
+		}
+		c.JSON(201, gin.H{})
+	})
+	if w := post(r, "k", `{"price":10}`); w.Code != 500 {
+		t.Fatalf("panicking handler got %d, want 500", w.Code)
+	}
+	if w := post(r, "k", `{"price":10}`); w.Code != 201 || calls != 2 {
+		t.Errorf("retry after a panic got %d after %d calls, want the handler to run again", w.Code, calls)
+	}
+}
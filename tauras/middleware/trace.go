@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	traceKey = "trace.id"
+
+	maxTraceIDLength = 128
+)
+
+// Trace gives every request a trace id, taken from the X-Request-Id header or generated.
+// The id is echoed back in X-Request-Id and travels with the events the request produces.
+func Trace() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader("X-Request-Id")
+		if id == "" || len(id) > maxTraceIDLength {
+			id = newTraceID()
+		}
+		c.Set(traceKey, id)
+		c.Header("X-Request-Id", id)
+		c.Next()
+	}
+}
+
+// TraceID returns the trace id of the request, empty when Trace did not run
+func TraceID(c *gin.Context) string {
+	return c.GetString(traceKey)
+}
+
+func newTraceID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
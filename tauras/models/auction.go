@@ -11,6 +11,17 @@ type Auction struct{
 	Image_url string `gorm:"not null"`
 	End_time time.Time `gorm:"not null"`
 	Current_price float64 `gorm:"type:decimal(10,2)"`
+	Event_seq uint64 `gorm:"not null;default:0"` // last sequence number handed out to an event of this auction
+	Status string `gorm:"type:varchar(16);not null;default:'live';index:idx_auctions_status_times"` // see services/lifecycle.go
+	Start_time *time.Time `gorm:"index:idx_auctions_status_times"` // nil means live from creation
+	Winner_id *uint64 // top bidder when the auction closed, nil when it went unsold
+	Closed_at *time.Time
+	Reserve_price *float64 `gorm:"type:decimal(10,2)"` // hidden minimum, never shown to bidders
+	Buy_now_price *float64 `gorm:"type:decimal(10,2)"` // optional price that ends the auction at once, see handlers/auction/buynow.go
+	Outcome string `gorm:"type:varchar(16);not null;default:''"` // sold or unsold once closed
+	Increment_rule string `gorm:"type:text"` // JSON bidding.IncrementRule, empty allows any higher bid
+	Soft_close_seconds int `gorm:"not null;default:0"` // a bid this close to end_time extends it, 0 disables soft close
+	Extension_seconds int `gorm:"not null;default:0"` // how far such a bid pushes end_time out
 }
 
 func (Auction) TableName() string {
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type IdempotencyKey struct {
+	Id              uint64    `gorm:"primaryKey;autoIncrement"`
+	User_id         uint64    `gorm:"not null;uniqueIndex:idx_idempotency_user_key"`
+	Idempotency_key string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_user_key"`
+	Request_hash    string    `gorm:"type:char(64);not null"`
+	Status_code     int       `gorm:"not null;default:0"` // 0 while the first request is still running
+	Response_body   []byte    `gorm:"type:mediumblob"`
+	Created_at      time.Time `gorm:"not null"`
+	Expires_at      time.Time `gorm:"not null;index"`
+}
+
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys";
+}
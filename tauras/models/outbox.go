@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type OutboxMessage struct {
+	Id              uint64     `gorm:"primaryKey;autoIncrement"`
+	Topic           string     `gorm:"type:varchar(255);not null"`
+	Msg_key         []byte     `gorm:"type:varbinary(255)"`
+	Headers         string     `gorm:"type:text"`
+	Payload         []byte     `gorm:"type:mediumblob;not null"`
+	Created_at      time.Time  `gorm:"not null"`
+	Next_attempt_at time.Time  `gorm:"not null;index"`
+	Attempts        int        `gorm:"not null;default:0"`
+	Last_error      string     `gorm:"type:text"`
+	Delivered_at    *time.Time `gorm:"index"`
+}
+
+func (OutboxMessage) TableName() string {
+	return "outbox";
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type Session struct {
+	Id           uint64     `gorm:"primaryKey;autoIncrement"`
+	User_id      uint64     `gorm:"not null;index"`
+	Token_hash   string     `gorm:"type:char(64);not null;uniqueIndex"` // sha256 of the session token
+	User_agent   string     `gorm:"type:varchar(255)"`
+	Ip           string     `gorm:"type:varchar(64)"`
+	Created_at   time.Time  `gorm:"not null"`
+	Last_seen_at time.Time  `gorm:"not null"`
+	Expires_at   time.Time  `gorm:"not null;index"`
+	Revoked_at   *time.Time
+}
+
+func (Session) TableName() string {
+	return "sessions";
+}
@@ -1,14 +1,24 @@
 package models
 
-import "time"
+import (
+	"gemini/events"
+	"time"
+)
 
 type User struct {
 	Id uint `gorm:"primaryKey;autoIncrement"`
 	Email string `gorm:"not null"` 
 	Password_hash string `gorm:"not null"`
+	Display_name string `gorm:"type:varchar(64);uniqueIndex"` // public alias shown to other bidders, NULL for users created before aliases
 	Created_at time.Time `gorm:"autoUpdateTime"`
 }
 
 func(User) TableName() string {
 	return "users";
+}
+
+// Alias is the public name other users see, never the email or the raw id.
+// Users created before display names existed get a stable pseudonym.
+func (u User) Alias() string {
+	return events.Alias(uint64(u.Id), u.Display_name)
 }
\ No newline at end of file
@@ -2,8 +2,10 @@ package routes
 
 import (
 	"fmt"
+	"tauras/handlers/admin"
 	"tauras/handlers/auction"
 	"tauras/handlers/users"
+	"tauras/middleware"
 	t "tauras/types"
 
 	"github.com/gin-gonic/gin"
@@ -22,27 +24,58 @@ func SetupRoutes(r *gin.Engine , ctx *t.AppContext){
 		userGroup.POST("/login", func(c *gin.Context) {
 			users.HandleLogin(c, ctx)
 		} )
-		userGroup.GET("/dashboard", func(c *gin.Context) {
+	};
+
+	authedUserGroup := r.Group("api/user", middleware.RequireAuth(ctx))
+	{
+		authedUserGroup.GET("/dashboard", func(c *gin.Context) {
 			users.HandleDashboard(c, ctx)
 		})
+		authedUserGroup.POST("/logout", func(c *gin.Context) {
+			users.HandleLogout(c, ctx)
+		})
+		authedUserGroup.POST("/logout-all", func(c *gin.Context) {
+			users.HandleLogoutAll(c, ctx)
+		})
+		authedUserGroup.GET("/sessions", func(c *gin.Context) {
+			users.HandleListSessions(c, ctx)
+		})
+		authedUserGroup.DELETE("/sessions/:id", func(c *gin.Context) {
+			users.HandleRevokeSession(c, ctx)
+		})
 	};
 
 	auctionGroup := r.Group("api/auction/")
+	{
+		auctionGroup.GET("/:id", middleware.OptionalAuth(ctx), func(c *gin.Context) {
+			auction.HandleGetAuction(c , ctx)
+		});
+	};
+
+	authedAuctionGroup := r.Group("api/auction/", middleware.RequireAuth(ctx))
 	{
 
-		auctionGroup.POST("/bid", func(c *gin.Context) {
+		authedAuctionGroup.POST("/bid", middleware.Idempotency(ctx), func(c *gin.Context) {
 			fmt.Println("Received bid request");
 			auction.BidHandler(c, ctx);
 		});
 
-		auctionGroup.POST("/create", func(c *gin.Context){
+		authedAuctionGroup.POST("/create", middleware.Idempotency(ctx), func(c *gin.Context){
 			auction.HandleCreateAuction(c, ctx)
 		});
-		auctionGroup.GET("/:id", func(c *gin.Context) {
-			auction.HandleGetAuction(c , ctx)
+
+		authedAuctionGroup.POST("/:id/buy-now", middleware.Idempotency(ctx), func(c *gin.Context){
+			auction.HandleBuyNow(c, ctx)
 		});
 	};
 
+	adminGroup := r.Group("api/admin")
+	{
+		adminGroup.POST("/loadtest/sessions", func(c *gin.Context) {
+			admin.HandleMintLoadTestSessions(c, ctx)
+		})
+	};
+
 	// later implementations 
 	// r.GET("/api/auction/:id/bids", handleGetAuctionBids)
 	// r.GET("/auction/:id", handleAuctionWebsocket)
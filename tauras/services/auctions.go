@@ -0,0 +1,39 @@
+package services
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+// AuctionConfig holds the marketplace rules shared by all auctions
+type AuctionConfig struct {
+	// BuyNowThreshold is the fraction of the buy-now price a bid has to exceed to take buy-now off the table
+	BuyNowThreshold float64
+}
+
+// AuctionConfigFromEnv reads BUY_NOW_THRESHOLD, a fraction between 0 and 1
+func AuctionConfigFromEnv() AuctionConfig {
+	return AuctionConfig{
+		BuyNowThreshold: fractionFromEnv("BUY_NOW_THRESHOLD", 0.5),
+	}
+}
+
+func fractionFromEnv(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f <= 0 || f > 1 {
+		log.Printf("invalid %s=%q, using %v", key, v, def)
+		return def
+	}
+	return f
+}
+
+// BuyNowAvailable reports whether buy-now can still be used given the highest bid from someone other
+// than the seller, 0 when nobody has bid yet
+func (c AuctionConfig) BuyNowAvailable(buyNowPrice, topBid float64) bool {
+	return topBid <= buyNowPrice*c.BuyNowThreshold
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// SigningKey is one HMAC key used for session cookies, identified by ID in the cookie value
+type SigningKey struct {
+	ID     string
+	Secret []byte
+}
+
+// CookieConfig controls how the session cookie is signed and which attributes it carries
+type CookieConfig struct {
+	// Keys[0] signs new cookies, every key is accepted when verifying so old keys can be rotated out
+	Keys   []SigningKey
+	Secure bool
+	// MaxAge in seconds, 0 means a browser-session cookie
+	MaxAge int
+}
+
+// CookieConfigFromEnv reads SESSION_KEYS ("id:secret,id:secret", newest first),
+// SESSION_COOKIE_SECURE and SESSION_COOKIE_MAX_AGE
+func CookieConfigFromEnv(ttl time.Duration) CookieConfig {
+	cfg := CookieConfig{
+		Secure: os.Getenv("SESSION_COOKIE_SECURE") == "true",
+		MaxAge: int(durationFromEnv("SESSION_COOKIE_MAX_AGE", ttl).Seconds()),
+	}
+	for _, pair := range strings.Split(os.Getenv("SESSION_KEYS"), ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || id == "" || secret == "" || strings.Contains(id, ".") {
+			continue
+		}
+		cfg.Keys = append(cfg.Keys, SigningKey{ID: id, Secret: []byte(secret)})
+	}
+	if len(cfg.Keys) == 0 {
+		log.Println("SESSION_KEYS not set, using a random signing key; sessions will not survive a restart")
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			panic(err)
+		}
+		cfg.Keys = []SigningKey{{ID: "dev", Secret: secret}}
+	}
+	return cfg
+}
+
+// newSessionToken returns 256 bits of randomness, url-safe encoded
+func newSessionToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is what gets stored server-side, so a leaked sessions table cannot be replayed
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// signCookie returns "<token>.<key id>.<mac>" signed with the newest key
+func (cfg CookieConfig) signCookie(token string) string {
+	key := cfg.Keys[0]
+	return token + "." + key.ID + "." + mac(key, token)
+}
+
+// verifyCookie checks the signature with whichever key signed it and returns the token
+func (cfg CookieConfig) verifyCookie(value string) (string, bool) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+	token, id, sig := parts[0], parts[1], parts[2]
+	for _, key := range cfg.Keys {
+		if key.ID != id {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(mac(key, token)), []byte(sig)) == 1 {
+			return token, true
+		}
+		return "", false
+	}
+	return "", false
+}
+
+func mac(key SigningKey, token string) string {
+	h := hmac.New(sha256.New, key.Secret)
+	h.Write([]byte(key.ID + "." + token))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// IdempotencyRecord is the stored outcome of the first request made with an Idempotency-Key
+type IdempotencyRecord struct {
+	UserID      uint64
+	Key         string
+	RequestHash string
+	// StatusCode is 0 while the first request is still being processed
+	StatusCode int
+	Response   []byte
+	// ExpiresAt is the end of the lease while the request is in progress, and of the TTL once it completed
+	ExpiresAt time.Time
+}
+
+// IdempotencyStore remembers responses per (user, key) for a limited window
+type IdempotencyStore interface {
+	// Begin reserves the key for the lease. If the key is already known the existing record is returned instead.
+	Begin(userID uint64, key, requestHash string) (*IdempotencyRecord, error)
+	// Complete stores the response for a reserved key and keeps it for the TTL
+	Complete(userID uint64, key string, status int, body []byte) error
+	// Release forgets a reserved key so the request can be retried
+	Release(userID uint64, key string) error
+	// PurgeExpired deletes records older than the window
+	PurgeExpired() (int64, error)
+}
+
+// MySQLIdempotencyStore keeps records in the idempotency_keys table
+type MySQLIdempotencyStore struct {
+	db    *sql.DB
+	ttl   time.Duration
+	lease time.Duration
+}
+
+func NewMySQLIdempotencyStore(db *sql.DB, ttl, lease time.Duration) *MySQLIdempotencyStore {
+	return &MySQLIdempotencyStore{db: db, ttl: ttl, lease: lease}
+}
+
+func (s *MySQLIdempotencyStore) Begin(userID uint64, key, requestHash string) (*IdempotencyRecord, error) {
+	// the record found after a duplicate insert can be released before it is read, then try to reserve again
+	for {
+		rec, err := s.begin(userID, key, requestHash)
+		if err != sql.ErrNoRows {
+			return rec, err
+		}
+	}
+}
+
+func (s *MySQLIdempotencyStore) begin(userID uint64, key, requestHash string) (*IdempotencyRecord, error) {
+	now := time.Now().UTC()
+	// an expired record no longer counts, drop it so the key can be reserved again.
+	// This includes reservations whose request died before completing or releasing them.
+	if _, err := s.db.Exec(
+		"DELETE FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ? AND expires_at <= ?",
+		userID, key, now,
+	); err != nil {
+		return nil, err
+	}
+
+	_, err := s.db.Exec(
+		`INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash, status_code, created_at, expires_at)
+		 VALUES (?, ?, ?, 0, ?, ?)`,
+		userID, key, requestHash, now, now.Add(s.lease),
+	)
+	if err == nil {
+		return nil, nil
+	}
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) || myErr.Number != 1062 { // 1062 = duplicate entry
+		return nil, err
+	}
+
+	rec := IdempotencyRecord{UserID: userID, Key: key}
+	err = s.db.QueryRow(
+		`SELECT request_hash, status_code, response_body, expires_at
+		 FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ?`,
+		userID, key,
+	).Scan(&rec.RequestHash, &rec.StatusCode, &rec.Response, &rec.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (s *MySQLIdempotencyStore) Complete(userID uint64, key string, status int, body []byte) error {
+	_, err := s.db.Exec(
+		`UPDATE idempotency_keys SET status_code = ?, response_body = ?, expires_at = ?
+		 WHERE user_id = ? AND idempotency_key = ? AND status_code = 0`,
+		status, body, time.Now().UTC().Add(s.ttl), userID, key,
+	)
+	return err
+}
+
+func (s *MySQLIdempotencyStore) Release(userID uint64, key string) error {
+	_, err := s.db.Exec("DELETE FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ? AND status_code = 0", userID, key)
+	return err
+}
+
+func (s *MySQLIdempotencyStore) PurgeExpired() (int64, error) {
+	res, err := s.db.Exec("DELETE FROM idempotency_keys WHERE expires_at <= ?", time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// MemoryIdempotencyStore keeps records in process memory, for tests and local runs without MySQL
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	lease   time.Duration
+	records map[memoryIdempotencyKey]*IdempotencyRecord
+}
+
+type memoryIdempotencyKey struct {
+	userID uint64
+	key    string
+}
+
+func NewMemoryIdempotencyStore(ttl, lease time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{ttl: ttl, lease: lease, records: map[memoryIdempotencyKey]*IdempotencyRecord{}}
+}
+
+func (s *MemoryIdempotencyStore) Begin(userID uint64, key, requestHash string) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := memoryIdempotencyKey{userID, key}
+	now := time.Now().UTC()
+	if rec, ok := s.records[k]; ok && now.Before(rec.ExpiresAt) {
+		copied := *rec
+		return &copied, nil
+	}
+	s.records[k] = &IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   now.Add(s.lease),
+	}
+	return nil, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(userID uint64, key string, status int, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rec, ok := s.records[memoryIdempotencyKey{userID, key}]; ok && rec.StatusCode == 0 {
+		rec.StatusCode = status
+		rec.Response = body
+		rec.ExpiresAt = time.Now().UTC().Add(s.ttl)
+	}
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(userID uint64, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := memoryIdempotencyKey{userID, key}
+	if rec, ok := s.records[k]; ok && rec.StatusCode == 0 {
+		delete(s.records, k)
+	}
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) PurgeExpired() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	var n int64
+	for k, rec := range s.records {
+		if !now.Before(rec.ExpiresAt) {
+			delete(s.records, k)
+			n++
+		}
+	}
+	return n, nil
+}
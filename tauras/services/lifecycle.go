@@ -0,0 +1,83 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"gemini/events"
+	"time"
+)
+
+// Auction statuses. The status column only moves along the transitions below.
+const (
+	// StatusDraft auctions are not visible to bidders yet
+	StatusDraft = "draft"
+	// StatusScheduled auctions open for bids at their start time
+	StatusScheduled = "scheduled"
+	// StatusLive auctions accept bids until their end time
+	StatusLive = "live"
+	// StatusClosed auctions are over and have their winner recorded
+	StatusClosed = "closed"
+	// StatusSettled auctions have been paid for and handed over
+	StatusSettled = "settled"
+	// StatusCancelled auctions were withdrawn before they closed
+	StatusCancelled = "cancelled"
+)
+
+var auctionTransitions = map[string][]string{
+	StatusDraft:     {StatusScheduled, StatusLive, StatusCancelled},
+	StatusScheduled: {StatusLive, StatusCancelled},
+	StatusLive:      {StatusClosed, StatusCancelled},
+	StatusClosed:    {StatusSettled},
+}
+
+// ErrInvalidTransition is returned for a status change the state machine does not allow
+var ErrInvalidTransition = errors.New("invalid auction status transition")
+
+// ErrStaleStatus is returned when the auction left the expected status before the update ran
+var ErrStaleStatus = errors.New("auction status changed concurrently")
+
+// CanTransition reports whether an auction may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, next := range auctionTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionAuction moves an auction from one status to another inside the caller's transaction.
+// The update is conditional on the current status, so of two concurrent transitions only one applies.
+func TransitionAuction(tx *sql.Tx, auctionID int64, from, to string) error {
+	if !CanTransition(from, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+	}
+	res, err := tx.Exec("UPDATE auctions SET status = ? WHERE id = ? AND status = ?", to, auctionID, from)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrStaleStatus
+	}
+	return nil
+}
+
+// CloseAuction closes a live auction inside the caller's transaction, records the outcome and
+// publishes AuctionClosed. The caller holds the auction row lock and has picked the winner.
+func CloseAuction(tx *sql.Tx, auctionID int64, winnerID *uint64, closed events.AuctionClosed, traceID string) error {
+	if err := TransitionAuction(tx, auctionID, StatusLive, StatusClosed); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"UPDATE auctions SET winner_id = ?, outcome = ?, closed_at = ? WHERE id = ?",
+		winnerID, closed.Outcome, time.Now().UTC(), auctionID,
+	); err != nil {
+		return err
+	}
+	return EnqueueAuctionEvent(tx, auctionID, closed, traceID)
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"gemini/events"
+	"log"
+	"strconv"
+	"time"
+)
+
+// EnqueueOutbox records an event inside the caller's transaction.
+// It only gets published once the transaction commits, and is never lost if kafka is down.
+func EnqueueOutbox(tx *sql.Tx, msg Message) error {
+	headers, err := json.Marshal(msg.Headers)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	_, err = tx.Exec(
+		"INSERT INTO outbox (topic, msg_key, headers, payload, created_at, next_attempt_at) VALUES (?, ?, ?, ?, ?, ?)",
+		msg.Topic, msg.Key, headers, msg.Value, now, now,
+	)
+	return err
+}
+
+// EnqueueEvent records a domain event in the outbox, keyed by its auction so
+// all events of an auction are published to the same partition in order
+func EnqueueEvent(tx *sql.Tx, env events.Envelope, traceID string) error {
+	value, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	return EnqueueOutbox(tx, Message{
+		Topic: events.Topic,
+		Key:   []byte(env.AuctionID),
+		Headers: map[string]string{
+			HeaderEventType:     env.Type,
+			HeaderSchemaVersion: strconv.Itoa(env.Version),
+			HeaderTraceID:       traceID,
+			HeaderContentType:   events.ContentTypeJSON,
+		},
+		Value: value,
+	})
+}
+
+// EnqueueAuctionEvent takes the auction's next sequence number, wraps ev in an envelope and writes it to the outbox
+func EnqueueAuctionEvent(tx *sql.Tx, auctionID int64, ev events.Event, traceID string) error {
+	seq, err := NextAuctionSeq(tx, auctionID)
+	if err != nil {
+		return err
+	}
+	env, err := events.New(strconv.FormatInt(auctionID, 10), seq, ev)
+	if err != nil {
+		return err
+	}
+	return EnqueueEvent(tx, env, traceID)
+}
+
+// OutboxRelay publishes undelivered outbox rows in insertion order per message key and marks them delivered.
+// Delivery is at-least-once: a crash between publish and the update re-sends the row. Consumers de-duplicate
+// by the envelope's per-auction seq, as Pisces does.
+type OutboxRelay struct {
+	db        *sql.DB
+	publisher EventPublisher
+	// Interval is how long the relay sleeps when the outbox is empty
+	Interval time.Duration
+	// BatchSize is how many rows are read per poll
+	BatchSize int
+	// Retention is how long delivered rows are kept before PurgeExpired removes them
+	Retention time.Duration
+	// ContentType is the wire encoding of domain events, rows are stored as JSON and transcoded on publish
+	ContentType string
+}
+
+func NewOutboxRelay(db *sql.DB, publisher EventPublisher) *OutboxRelay {
+	return &OutboxRelay{
+		db:          db,
+		publisher:   publisher,
+		Interval:    200 * time.Millisecond,
+		BatchSize:   100,
+		Retention:   24 * time.Hour,
+		ContentType: events.ContentTypeJSON,
+	}
+}
+
+type outboxRow struct {
+	id            uint64
+	topic         string
+	key           []byte
+	headers       sql.NullString
+	payload       []byte
+	attempts      int
+	nextAttemptAt time.Time
+}
+
+// Run relays until ctx is cancelled
+func (r *OutboxRelay) Run(ctx context.Context) {
+	for {
+		n, err := r.relayBatch()
+		if err != nil {
+			log.Printf("outbox relay: %v", err)
+		}
+		if n == r.BatchSize {
+			// there is probably more waiting, go again straight away
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.Interval):
+		}
+	}
+}
+
+// relayBatch publishes one batch of the oldest undelivered rows and returns how many were delivered.
+// A row that failed, or is still backing off from a failure, blocks every later row with the same key
+// until it goes through, so the events of an auction are never published out of order. Rows of other
+// keys keep flowing, the query leaves out rows stuck behind a backing-off row so they cannot fill the batch.
+func (r *OutboxRelay) relayBatch() (int, error) {
+	rows, err := r.db.Query(
+		`SELECT o.id, o.topic, o.msg_key, o.headers, o.payload, o.attempts, o.next_attempt_at FROM outbox o
+		 WHERE o.delivered_at IS NULL AND NOT EXISTS (
+		   SELECT 1 FROM outbox f
+		   WHERE f.delivered_at IS NULL AND f.next_attempt_at > ? AND f.msg_key <=> o.msg_key AND f.id < o.id
+		 )
+		 ORDER BY o.id LIMIT ?`,
+		time.Now().UTC(), r.BatchSize,
+	)
+	if err != nil {
+		return 0, err
+	}
+	var batch []outboxRow
+	for rows.Next() {
+		var row outboxRow
+		if err := rows.Scan(&row.id, &row.topic, &row.key, &row.headers, &row.payload, &row.attempts, &row.nextAttemptAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var (
+		delivered int
+		firstErr  error
+		// keys whose oldest undelivered row is waiting for a retry
+		blocked = map[string]bool{}
+	)
+	now := time.Now().UTC()
+	for _, row := range batch {
+		key := string(row.key)
+		if blocked[key] {
+			continue
+		}
+		if row.nextAttemptAt.After(now) {
+			blocked[key] = true
+			continue
+		}
+		if err := r.publish(row); err != nil {
+			r.markFailed(row, err)
+			blocked[key] = true
+			if firstErr == nil {
+				firstErr = fmt.Errorf("publishing outbox row %d: %w", row.id, err)
+			}
+			continue
+		}
+		if _, err := r.db.Exec("UPDATE outbox SET delivered_at = ?, attempts = attempts + 1 WHERE id = ?", time.Now().UTC(), row.id); err != nil {
+			// without the update the row would be published again after later ones, stop here
+			return delivered, err
+		}
+		delivered++
+	}
+	return delivered, firstErr
+}
+
+// publish hands one row to the publisher and waits for the acknowledgement
+func (r *OutboxRelay) publish(row outboxRow) error {
+	msg := Message{Topic: row.topic, Key: row.key, Value: row.payload}
+	// rows written before headers existed have none
+	if row.headers.Valid && row.headers.String != "" {
+		if err := json.Unmarshal([]byte(row.headers.String), &msg.Headers); err != nil {
+			return fmt.Errorf("decoding headers: %w", err)
+		}
+	}
+	if err := r.encode(&msg); err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return r.publisher.Publish(ctx, msg)
+}
+
+// encode transcodes a JSON domain event to the relay's content type.
+// Rows without a content-type header predate envelopes and go out untouched.
+func (r *OutboxRelay) encode(msg *Message) error {
+	current := msg.Headers[HeaderContentType]
+	if current == "" || current == r.ContentType {
+		return nil
+	}
+	env, err := events.Unmarshal(msg.Value, current)
+	if err != nil {
+		return err
+	}
+	value, err := events.Marshal(env, r.ContentType)
+	if err != nil {
+		return err
+	}
+	msg.Value = value
+	msg.Headers[HeaderContentType] = r.ContentType
+	return nil
+}
+
+// markFailed schedules the row for a retry with exponential backoff, capped at a minute
+func (r *OutboxRelay) markFailed(row outboxRow, cause error) {
+	backoff := time.Second << min(row.attempts, 6)
+	if backoff > time.Minute {
+		backoff = time.Minute
+	}
+	_, err := r.db.Exec(
+		"UPDATE outbox SET attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?",
+		cause.Error(), time.Now().UTC().Add(backoff), row.id,
+	)
+	if err != nil {
+		log.Printf("outbox relay: error recording failure for row %d: %v", row.id, err)
+	}
+}
+
+// PurgeExpired deletes delivered rows older than Retention
+func (r *OutboxRelay) PurgeExpired() (int64, error) {
+	res, err := r.db.Exec("DELETE FROM outbox WHERE delivered_at IS NOT NULL AND delivered_at <= ?", time.Now().UTC().Add(-r.Retention))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"gemini/events"
+	"testing"
+)
+
+func TestOutboxRelayPublishTranscodes(t *testing.T) {
+	env, err := events.New("7", 3, events.BidPlaced{BidID: 11, Bidder: "alice", Price: 12.5, MinNextBid: 13})
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, _ := json.Marshal(env)
+	headers, _ := json.Marshal(map[string]string{
+		HeaderEventType:   env.Type,
+		HeaderContentType: events.ContentTypeJSON,
+	})
+
+	p := NewChannelPublisher(1)
+	sub := p.Subscribe()
+	relay := NewOutboxRelay(nil, p)
+	relay.ContentType = events.ContentTypeProtobuf
+
+	row := outboxRow{
+		id:      1,
+		topic:   events.Topic,
+		key:     []byte(env.AuctionID),
+		headers: sql.NullString{String: string(headers), Valid: true},
+		payload: value,
+	}
+	if err := relay.publish(row); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	msg := <-sub
+	if string(msg.Key) != "7" {
+		t.Errorf("key = %q, want 7", msg.Key)
+	}
+	if ct := msg.Headers[HeaderContentType]; ct != events.ContentTypeProtobuf {
+		t.Fatalf("content-type = %q", ct)
+	}
+	got, err := events.Unmarshal(msg.Value, events.ContentTypeProtobuf)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.ID != env.ID || got.Seq != env.Seq || string(got.Payload) != string(env.Payload) {
+		t.Errorf("got %+v, want %+v", got, env)
+	}
+}
+
+func TestOutboxRelayPublishLegacyRow(t *testing.T) {
+	p := NewChannelPublisher(1)
+	sub := p.Subscribe()
+	relay := NewOutboxRelay(nil, p)
+	relay.ContentType = events.ContentTypeProtobuf
+
+	// rows from before headers existed go out untouched
+	legacy := []byte(`{"id":5,"auction_id":"7","price":10}`)
+	if err := relay.publish(outboxRow{id: 1, topic: events.Topic, payload: legacy}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if msg := <-sub; string(msg.Value) != string(legacy) || len(msg.Headers) != 0 {
+		t.Errorf("legacy row changed: %+v", msg)
+	}
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// Header names set on every event
+const (
+	HeaderEventType     = "event-type"
+	HeaderSchemaVersion = "schema-version"
+	HeaderTraceID       = "trace-id"
+	HeaderContentType   = "content-type"
+)
+
+// Message is one event headed for a topic.
+// Messages with the same Key land on the same partition, so they are consumed in the order they were published.
+type Message struct {
+	Topic   string
+	Key     []byte
+	Headers map[string]string
+	Value   []byte
+}
+
+// EventPublisher delivers events to the message bus.
+// KafkaPublisher is used in production, ChannelPublisher in tests and single-binary dev mode, LogPublisher when no bus is wanted.
+type EventPublisher interface {
+	// Publish blocks until the message is acknowledged or ctx is done
+	Publish(ctx context.Context, msg Message) error
+	Close()
+}
+
+// ChannelPublisher hands every message to in-process subscribers.
+// Publish blocks while a subscriber is full, so every subscriber has to keep reading.
+type ChannelPublisher struct {
+	mu     sync.RWMutex
+	subs   []chan Message
+	buffer int
+}
+
+func NewChannelPublisher(buffer int) *ChannelPublisher {
+	return &ChannelPublisher{buffer: buffer}
+}
+
+// Subscribe returns a channel that receives every message published from now on
+func (p *ChannelPublisher) Subscribe() <-chan Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ch := make(chan Message, p.buffer)
+	p.subs = append(p.subs, ch)
+	return ch
+}
+
+func (p *ChannelPublisher) Publish(ctx context.Context, msg Message) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, ch := range p.subs {
+		select {
+		case ch <- msg:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (p *ChannelPublisher) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, ch := range p.subs {
+		close(ch)
+	}
+	p.subs = nil
+}
+
+// LogPublisher only logs events, for running Tauras without a broker
+type LogPublisher struct{}
+
+func (LogPublisher) Publish(ctx context.Context, msg Message) error {
+	log.Printf("event [%s] key=%s headers=%v: %s", msg.Topic, msg.Key, msg.Headers, msg.Value)
+	return nil
+}
+
+func (LogPublisher) Close() {}
+
+// LogMessages logs every message received on ch until it is closed,
+// the in-process subscriber of a ChannelPublisher when no other consumer runs
+func LogMessages(ch <-chan Message) {
+	for msg := range ch {
+		LogPublisher{}.Publish(context.Background(), msg)
+	}
+}
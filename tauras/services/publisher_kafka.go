@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// KafkaPublisher produces to kafka and waits for the delivery report of every message
+type KafkaPublisher struct {
+	producer *kafka.Producer
+}
+
+func NewKafkaPublisher(brokers string) (*KafkaPublisher, error) {
+	if brokers == "" {
+		return nil, fmt.Errorf("kafka brokers are not set in KAFKA_BROKERS")
+	}
+	p, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": brokers})
+	if err != nil {
+		return nil, err
+	}
+	// reports for messages produced without their own delivery channel end up here
+	go func() {
+		for e := range p.Events() {
+			if ev, ok := e.(*kafka.Message); ok && ev.TopicPartition.Error != nil {
+				log.Println("Delivery failed:", ev.TopicPartition)
+			}
+		}
+	}()
+	return &KafkaPublisher{producer: p}, nil
+}
+
+func (p *KafkaPublisher) Publish(ctx context.Context, msg Message) error {
+	delivery := make(chan kafka.Event, 1)
+	topic := msg.Topic
+	headers := make([]kafka.Header, 0, len(msg.Headers))
+	for k, v := range msg.Headers {
+		headers = append(headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	// PartitionAny with a key uses the default partitioner, which hashes the key to pick the partition
+	err := p.producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            msg.Key,
+		Headers:        headers,
+		Value:          msg.Value,
+	}, delivery)
+	if err != nil {
+		return err
+	}
+	select {
+	case e := <-delivery:
+		m, ok := e.(*kafka.Message)
+		if !ok {
+			return fmt.Errorf("unexpected delivery event %v", e)
+		}
+		return m.TopicPartition.Error
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *KafkaPublisher) Close() {
+	p.producer.Flush(5000)
+	p.producer.Close()
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChannelPublisherFansOutInOrder(t *testing.T) {
+	p := NewChannelPublisher(4)
+	a, b := p.Subscribe(), p.Subscribe()
+
+	for _, v := range []string{"1", "2", "3"} {
+		if err := p.Publish(context.Background(), Message{Topic: "bids", Value: []byte(v)}); err != nil {
+			t.Fatalf("publish %s: %v", v, err)
+		}
+	}
+	for _, ch := range []<-chan Message{a, b} {
+		for _, want := range []string{"1", "2", "3"} {
+			if got := string((<-ch).Value); got != want {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+		}
+	}
+
+	p.Close()
+	if _, ok := <-a; ok {
+		t.Fatal("subscription still open after Close")
+	}
+}
+
+func TestChannelPublisherGivesUpWhenSubscriberIsFull(t *testing.T) {
+	p := NewChannelPublisher(1)
+	p.Subscribe()
+	if err := p.Publish(context.Background(), Message{}); err != nil {
+		t.Fatalf("first publish: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.Publish(ctx, Message{}); err == nil {
+		t.Fatal("publish to a full subscriber did not fail")
+	}
+}
+
+func TestLogMessagesKeepsMemoryPublisherDraining(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stderr)
+
+	p := NewChannelPublisher(1)
+	sub := p.Subscribe()
+	done := make(chan struct{})
+	go func() {
+		LogMessages(sub)
+		close(done)
+	}()
+
+	// more messages than the buffer holds, publishing only succeeds while the subscriber reads
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for _, v := range []string{"one", "two", "three"} {
+		if err := p.Publish(ctx, Message{Topic: "bids", Value: []byte(v)}); err != nil {
+			t.Fatalf("publish %s: %v", v, err)
+		}
+	}
+	p.Close()
+	<-done
+
+	if got := strings.Count(out.String(), "event [bids]"); got != 3 {
+		t.Errorf("logged %d events, want 3:\n%s", got, out.String())
+	}
+}
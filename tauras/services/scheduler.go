@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"gemini/bidding"
+	"gemini/events"
+	"log"
+	"time"
+)
+
+// AuctionScheduler moves auctions through their lifecycle at their start and end times.
+// Every transition locks the auction row and re-checks its status, so several Tauras
+// instances can run a scheduler side by side and each auction still transitions once.
+type AuctionScheduler struct {
+	db *sql.DB
+	// Interval is how often due auctions are looked for
+	Interval time.Duration
+	// BatchSize is how many due auctions are handled per tick and transition
+	BatchSize int
+}
+
+func NewAuctionScheduler(db *sql.DB) *AuctionScheduler {
+	return &AuctionScheduler{db: db, Interval: time.Second, BatchSize: 100}
+}
+
+// Run schedules until ctx is cancelled
+func (s *AuctionScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		s.tick()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *AuctionScheduler) tick() {
+	now := time.Now().UTC()
+	s.each("starting", "SELECT id FROM auctions WHERE status = ? AND start_time <= ? ORDER BY start_time LIMIT ?",
+		StatusScheduled, now, s.startAuction)
+	s.each("closing", "SELECT id FROM auctions WHERE status = ? AND end_time <= ? ORDER BY end_time LIMIT ?",
+		StatusLive, now, s.closeAuction)
+}
+
+// each runs fn for every auction id the query returns, logging failures without stopping
+func (s *AuctionScheduler) each(what, query, status string, now time.Time, fn func(int64) error) {
+	rows, err := s.db.Query(query, status, now, s.BatchSize)
+	if err != nil {
+		log.Printf("scheduler: error selecting auctions due for %s: %v", what, err)
+		return
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			log.Printf("scheduler: error scanning auction id: %v", err)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	for _, id := range ids {
+		if err := fn(id); err != nil && !errors.Is(err, ErrStaleStatus) {
+			log.Printf("scheduler: error %s auction %d: %v", what, id, err)
+		}
+	}
+}
+
+// startAuction opens a scheduled auction for bids and publishes AuctionStarted
+func (s *AuctionScheduler) startAuction(auctionID int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var (
+		status    string
+		startTime sql.NullTime
+		endTime   time.Time
+		price     float64
+		rule      sql.NullString
+	)
+	err = tx.QueryRow(
+		`SELECT status, start_time, end_time, COALESCE(current_price, starting_price), increment_rule
+		 FROM auctions WHERE id = ? FOR UPDATE`,
+		auctionID,
+	).Scan(&status, &startTime, &endTime, &price, &rule)
+	if err != nil {
+		return err
+	}
+	if status != StatusScheduled || !startTime.Valid || startTime.Time.After(time.Now()) {
+		return ErrStaleStatus
+	}
+	if err := TransitionAuction(tx, auctionID, StatusScheduled, StatusLive); err != nil {
+		return err
+	}
+	increments, err := bidding.ParseIncrementRule(rule.String)
+	if err != nil {
+		return err
+	}
+	started := events.AuctionStarted{
+		StartTime:  startTime.Time.UTC(),
+		EndTime:    endTime.UTC(),
+		MinNextBid: increments.MinNextBid(price),
+	}
+	if err := EnqueueAuctionEvent(tx, auctionID, started, schedulerTraceID()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// closeAuction ends a live auction, records the winning bid and publishes AuctionClosed
+func (s *AuctionScheduler) closeAuction(auctionID int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var (
+		status   string
+		sellerID uint64
+		endTime  time.Time
+		reserve  sql.NullFloat64
+	)
+	// the row lock keeps bids out while the winner is picked
+	err = tx.QueryRow("SELECT status, user_id, end_time, reserve_price FROM auctions WHERE id = ? FOR UPDATE", auctionID).
+		Scan(&status, &sellerID, &endTime, &reserve)
+	if err != nil {
+		return err
+	}
+	if status != StatusLive || endTime.After(time.Now()) {
+		// closed by someone else, or the end time moved since the auction was selected
+		return ErrStaleStatus
+	}
+
+	// the seller's opening bid only sets the starting price, it never wins
+	var (
+		winnerID    uint64
+		price       float64
+		displayName sql.NullString
+	)
+	err = tx.QueryRow(
+		`SELECT b.user_id, b.price, u.display_name FROM bids b LEFT JOIN users u ON u.id = b.user_id
+		 WHERE b.auction_id = ? AND b.user_id <> ?
+		 ORDER BY b.price DESC, b.id ASC LIMIT 1`,
+		auctionID, sellerID,
+	).Scan(&winnerID, &price, &displayName)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	// without bids, or when the top bid is below the reserve, the item goes unsold
+	closed := events.AuctionClosed{Outcome: events.OutcomeUnsold, Reason: events.ReasonEnded}
+	var winner *uint64
+	if err == nil && (!reserve.Valid || price >= reserve.Float64) {
+		alias := events.Alias(winnerID, displayName.String)
+		closed.Winner, closed.Price, winner = &alias, &price, &winnerID
+		closed.Outcome = events.OutcomeSold
+	}
+
+	if err := CloseAuction(tx, auctionID, winner, closed, schedulerTraceID()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// schedulerTraceID marks events raised by the scheduler, which have no request to take a trace id from
+func schedulerTraceID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "scheduler-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,15 @@
+package services
+
+import "database/sql"
+
+// NextAuctionSeq bumps and returns the auction's event sequence number.
+// Every event about an auction takes the next number inside the transaction that produces it,
+// which gives Pisces a per-auction order clients can resume from.
+func NextAuctionSeq(tx *sql.Tx, auctionID int64) (uint64, error) {
+	if _, err := tx.Exec("UPDATE auctions SET event_seq = event_seq + 1 WHERE id = ?", auctionID); err != nil {
+		return 0, err
+	}
+	var seq uint64
+	err := tx.QueryRow("SELECT event_seq FROM auctions WHERE id = ?", auctionID).Scan(&seq)
+	return seq, err
+}
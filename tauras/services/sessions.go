@@ -1,63 +1,128 @@
 package services
 
 import (
+	"log"
 	"net/http"
 	"os"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Session struct {
-    UserID uint64
-}
-
-// In-memory session store (replace with DB or Redis for production)
-var sessions = map[string]Session{}
-
-// SessionService holds session-related logic
-type SessionService struct{}
-
-// ParseSessionCookie reads the session cookie and returns the session
-func (s *SessionService) ParseSessionCookie(c *gin.Context) *Session {
-    cookie, err := c.Request.Cookie("session")
-    if err != nil || cookie.Value == "" {
-        return nil
-    }
-    if sess, ok := sessions[cookie.Value]; ok {
-        return &sess
-    }
-    return nil
-}
-
-// CreateSession creates a new session token
-func (s *SessionService) CreateSession(userID uint64) string {
-    token := strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatInt(int64(userID), 36)
-    sessions[token] = Session{UserID: userID}
-    return token
-}
-
-// SetSessionCookie sets the session cookie on the response
-func (s *SessionService) SetSessionCookie(c *gin.Context, token string) {
-    http.SetCookie(c.Writer, &http.Cookie{
-        Name:     "session",
-        Value:    token,
-        Path:     "/",
-        HttpOnly: true,
-        SameSite: http.SameSiteLaxMode,
-    })
-}
-
-// RequireSession validates that a session exists, returns nil if unauthorized
-func (s *SessionService) RequireSession(c *gin.Context) *Session {
-    if os.Getenv("LOAD_TEST") == "true" {
-        return &Session{UserID: 0}
-    }
-    sess := s.ParseSessionCookie(c)
-    if sess == nil {
-        c.JSON(401, gin.H{"error": "Unauthorized"})
-        return nil
-    }
-    return sess
-}
\ No newline at end of file
+	ID         uint64
+	UserID     uint64
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+}
+
+// SessionOptions carries the client details recorded with a new session.
+// A zero TTL falls back to the configured default.
+type SessionOptions struct {
+	UserAgent string
+	IP        string
+	TTL       time.Duration
+}
+
+// SessionConfig controls how long sessions live and how often they are renewed
+type SessionConfig struct {
+	// TTL is how long a session stays valid after it was last renewed
+	TTL time.Duration
+	// RenewAfter is how stale last_seen has to be before a request slides the expiry forward
+	RenewAfter time.Duration
+	Cookie     CookieConfig
+}
+
+// SessionConfigFromEnv reads SESSION_TTL and SESSION_RENEW_AFTER (Go durations) plus the cookie settings
+func SessionConfigFromEnv() SessionConfig {
+	ttl := durationFromEnv("SESSION_TTL", 7*24*time.Hour)
+	return SessionConfig{
+		TTL:        ttl,
+		RenewAfter: durationFromEnv("SESSION_RENEW_AFTER", time.Minute),
+		Cookie:     CookieConfigFromEnv(ttl),
+	}
+}
+
+// SessionService is the session store used by the handlers.
+// MySQLSessionService is used in production, MemorySessionService for tests and local runs.
+type SessionService interface {
+	// CreateSession stores a new session for the user and returns its token
+	CreateSession(userID uint64, opts SessionOptions) (string, error)
+	// GetSession returns the live session for a token, or nil if it is unknown, expired or revoked.
+	// Looking a session up slides its expiry forward.
+	GetSession(token string) (*Session, error)
+	// ListSessions returns the live sessions of a user, most recently used first
+	ListSessions(userID uint64) ([]Session, error)
+	// RevokeSession ends a single session
+	RevokeSession(id uint64) error
+	// RevokeAllSessions ends every session of a user
+	RevokeAllSessions(userID uint64) error
+	// PurgeExpired deletes expired and revoked sessions
+	PurgeExpired() (int64, error)
+
+	ParseSessionCookie(c *gin.Context) *Session
+	SetSessionCookie(c *gin.Context, token string)
+	ClearSessionCookie(c *gin.Context)
+	// CookieValue returns the signed cookie value for a token, for clients that set the cookie themselves
+	CookieValue(token string) string
+}
+
+// parseSessionCookie verifies the signed session cookie and returns the session
+func parseSessionCookie(s SessionService, cfg CookieConfig, c *gin.Context) *Session {
+	cookie, err := c.Request.Cookie("session")
+	if err != nil || cookie.Value == "" {
+		return nil
+	}
+	token, ok := cfg.verifyCookie(cookie.Value)
+	if !ok {
+		return nil
+	}
+	sess, err := s.GetSession(token)
+	if err != nil {
+		log.Printf("error looking up session: %v", err)
+		return nil
+	}
+	return sess
+}
+
+// setSessionCookie sets the signed session cookie on the response
+func setSessionCookie(cfg CookieConfig, c *gin.Context, token string) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     "session",
+		Value:    cfg.signCookie(token),
+		Path:     "/",
+		MaxAge:   cfg.MaxAge,
+		Secure:   cfg.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// clearSessionCookie tells the browser to drop the session cookie
+func clearSessionCookie(cfg CookieConfig, c *gin.Context) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     "session",
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   cfg.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func durationFromEnv(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Printf("invalid %s=%q, using %s", key, v, def)
+		return def
+	}
+	return d
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MemorySessionService keeps sessions in process memory, for tests and local runs without MySQL
+type MemorySessionService struct {
+	mu       sync.Mutex
+	cfg      SessionConfig
+	nextID   uint64
+	sessions map[string]*memorySession // keyed by token hash
+}
+
+type memorySession struct {
+	Session
+	revoked bool
+}
+
+func NewMemorySessionService(cfg SessionConfig) *MemorySessionService {
+	return &MemorySessionService{cfg: cfg, sessions: map[string]*memorySession{}}
+}
+
+func (s *MemorySessionService) CreateSession(userID uint64, opts SessionOptions) (string, error) {
+	ttl := opts.TTL
+	if ttl == 0 {
+		ttl = s.cfg.TTL
+	}
+	now := time.Now().UTC()
+	token, err := newSessionToken()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	s.sessions[hashToken(token)] = &memorySession{Session: Session{
+		ID:         s.nextID,
+		UserID:     userID,
+		UserAgent:  opts.UserAgent,
+		IP:         opts.IP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}}
+	return token, nil
+}
+
+func (s *MemorySessionService) GetSession(token string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ms, ok := s.sessions[hashToken(token)]
+	now := time.Now().UTC()
+	if !ok || ms.revoked || !now.Before(ms.ExpiresAt) {
+		return nil, nil
+	}
+	if now.Sub(ms.LastSeenAt) >= s.cfg.RenewAfter {
+		ms.LastSeenAt = now
+		ms.ExpiresAt = now.Add(s.cfg.TTL)
+	}
+	sess := ms.Session
+	return &sess, nil
+}
+
+func (s *MemorySessionService) ListSessions(userID uint64) ([]Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	list := []Session{}
+	for _, ms := range s.sessions {
+		if ms.UserID == userID && !ms.revoked && now.Before(ms.ExpiresAt) {
+			list = append(list, ms.Session)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].LastSeenAt.After(list[j].LastSeenAt) })
+	return list, nil
+}
+
+func (s *MemorySessionService) RevokeSession(id uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ms := range s.sessions {
+		if ms.ID == id {
+			ms.revoked = true
+		}
+	}
+	return nil
+}
+
+func (s *MemorySessionService) RevokeAllSessions(userID uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ms := range s.sessions {
+		if ms.UserID == userID {
+			ms.revoked = true
+		}
+	}
+	return nil
+}
+
+func (s *MemorySessionService) PurgeExpired() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	var n int64
+	for hash, ms := range s.sessions {
+		if ms.revoked || !now.Before(ms.ExpiresAt) {
+			delete(s.sessions, hash)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemorySessionService) ParseSessionCookie(c *gin.Context) *Session {
+	return parseSessionCookie(s, s.cfg.Cookie, c)
+}
+
+func (s *MemorySessionService) SetSessionCookie(c *gin.Context, token string) {
+	setSessionCookie(s.cfg.Cookie, c, token)
+}
+
+func (s *MemorySessionService) ClearSessionCookie(c *gin.Context) {
+	clearSessionCookie(s.cfg.Cookie, c)
+}
+
+func (s *MemorySessionService) CookieValue(token string) string {
+	return s.cfg.Cookie.signCookie(token)
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestSessions(ttl, renewAfter time.Duration) *MemorySessionService {
+	return NewMemorySessionService(SessionConfig{TTL: ttl, RenewAfter: renewAfter})
+}
+
+func TestMemorySessionSlidingRenewal(t *testing.T) {
+	s := newTestSessions(time.Hour, 20*time.Millisecond)
+	token, err := s.CreateSession(1, SessionOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := s.GetSession(token)
+	if first == nil {
+		t.Fatal("new session not found")
+	}
+
+	// within RenewAfter the expiry stays put
+	again, _ := s.GetSession(token)
+	if !again.ExpiresAt.Equal(first.ExpiresAt) {
+		t.Errorf("expiry moved before RenewAfter: %v -> %v", first.ExpiresAt, again.ExpiresAt)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	renewed, _ := s.GetSession(token)
+	if renewed == nil || !renewed.ExpiresAt.After(first.ExpiresAt) || !renewed.LastSeenAt.After(first.LastSeenAt) {
+		t.Errorf("session was not renewed: %+v", renewed)
+	}
+}
+
+func TestMemorySessionExpiry(t *testing.T) {
+	s := newTestSessions(time.Hour, time.Hour)
+	token, err := s.CreateSession(1, SessionOptions{TTL: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess, _ := s.GetSession(token); sess == nil {
+		t.Fatal("session expired early")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if sess, _ := s.GetSession(token); sess != nil {
+		t.Error("expired session still valid")
+	}
+	if list, _ := s.ListSessions(1); len(list) != 0 {
+		t.Errorf("expired session listed: %+v", list)
+	}
+	if sess, _ := s.GetSession("unknown"); sess != nil {
+		t.Error("unknown token resolved to a session")
+	}
+}
+
+func TestMemorySessionRevoke(t *testing.T) {
+	s := newTestSessions(time.Hour, time.Hour)
+	a, _ := s.CreateSession(1, SessionOptions{})
+	b, _ := s.CreateSession(1, SessionOptions{})
+	other, _ := s.CreateSession(2, SessionOptions{})
+
+	sa, _ := s.GetSession(a)
+	if err := s.RevokeSession(sa.ID); err != nil {
+		t.Fatal(err)
+	}
+	if sess, _ := s.GetSession(a); sess != nil {
+		t.Error("revoked session still valid")
+	}
+	if sess, _ := s.GetSession(b); sess == nil {
+		t.Error("revoking one session ended another")
+	}
+
+	if err := s.RevokeAllSessions(1); err != nil {
+		t.Fatal(err)
+	}
+	if sess, _ := s.GetSession(b); sess != nil {
+		t.Error("session survived RevokeAllSessions")
+	}
+	if sess, _ := s.GetSession(other); sess == nil {
+		t.Error("RevokeAllSessions ended another user's session")
+	}
+}
+
+func TestMemorySessionPurgeExpired(t *testing.T) {
+	s := newTestSessions(time.Hour, time.Hour)
+	live, _ := s.CreateSession(1, SessionOptions{})
+	s.CreateSession(1, SessionOptions{TTL: time.Millisecond})
+	revoked, _ := s.CreateSession(2, SessionOptions{})
+	sr, _ := s.GetSession(revoked)
+	s.RevokeSession(sr.ID)
+	time.Sleep(5 * time.Millisecond)
+
+	n, err := s.PurgeExpired()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("purged %d sessions, want 2", n)
+	}
+	if sess, _ := s.GetSession(live); sess == nil {
+		t.Error("live session was purged")
+	}
+	if n, _ := s.PurgeExpired(); n != 0 {
+		t.Errorf("second purge removed %d sessions", n)
+	}
+}
+
+func TestMemorySessionConcurrentUse(t *testing.T) {
+	s := newTestSessions(time.Hour, 0)
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Add(1)
+		go func(userID uint64) {
+			defer wg.Done()
+			for range 50 {
+				token, err := s.CreateSession(userID, SessionOptions{})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				// RenewAfter 0 renews on every lookup, so lookups write concurrently too
+				if sess, _ := s.GetSession(token); sess == nil || sess.UserID != userID {
+					t.Errorf("lookup returned %+v for user %d", sess, userID)
+					return
+				}
+			}
+			s.ListSessions(userID)
+		}(uint64(i + 1))
+	}
+	wg.Wait()
+
+	seen := map[uint64]bool{}
+	for i := range 16 {
+		list, _ := s.ListSessions(uint64(i + 1))
+		if len(list) != 50 {
+			t.Errorf("user %d has %d sessions, want 50", i+1, len(list))
+		}
+		for _, sess := range list {
+			if seen[sess.ID] {
+				t.Errorf("session id %d handed out twice", sess.ID)
+			}
+			seen[sess.ID] = true
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MySQLSessionService stores sessions in the sessions table so they survive restarts
+type MySQLSessionService struct {
+	db  *sql.DB
+	cfg SessionConfig
+}
+
+func NewMySQLSessionService(db *sql.DB, cfg SessionConfig) *MySQLSessionService {
+	return &MySQLSessionService{db: db, cfg: cfg}
+}
+
+func (s *MySQLSessionService) CreateSession(userID uint64, opts SessionOptions) (string, error) {
+	ttl := opts.TTL
+	if ttl == 0 {
+		ttl = s.cfg.TTL
+	}
+	now := time.Now().UTC()
+	token, err := newSessionToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO sessions (user_id, token_hash, user_agent, ip, created_at, last_seen_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		userID, hashToken(token), truncate(opts.UserAgent, 255), truncate(opts.IP, 64), now, now, now.Add(ttl),
+	)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *MySQLSessionService) GetSession(token string) (*Session, error) {
+	var sess Session
+	err := s.db.QueryRow(
+		`SELECT id, user_id, user_agent, ip, created_at, last_seen_at, expires_at
+		 FROM sessions WHERE token_hash = ? AND revoked_at IS NULL AND expires_at > ? LIMIT 1`,
+		hashToken(token), time.Now().UTC(),
+	).Scan(&sess.ID, &sess.UserID, &sess.UserAgent, &sess.IP, &sess.CreatedAt, &sess.LastSeenAt, &sess.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// sliding renewal, only written once per RenewAfter to keep hot paths cheap
+	now := time.Now().UTC()
+	if now.Sub(sess.LastSeenAt) >= s.cfg.RenewAfter {
+		sess.LastSeenAt = now
+		sess.ExpiresAt = now.Add(s.cfg.TTL)
+		if _, err := s.db.Exec(
+			"UPDATE sessions SET last_seen_at = ?, expires_at = ? WHERE id = ?",
+			sess.LastSeenAt, sess.ExpiresAt, sess.ID,
+		); err != nil {
+			return nil, err
+		}
+	}
+	return &sess, nil
+}
+
+func (s *MySQLSessionService) ListSessions(userID uint64) ([]Session, error) {
+	rows, err := s.db.Query(
+		`SELECT id, user_id, user_agent, ip, created_at, last_seen_at, expires_at
+		 FROM sessions WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		 ORDER BY last_seen_at DESC`,
+		userID, time.Now().UTC(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Session{}
+	for rows.Next() {
+		var sess Session
+		if err := rows.Scan(&sess.ID, &sess.UserID, &sess.UserAgent, &sess.IP, &sess.CreatedAt, &sess.LastSeenAt, &sess.ExpiresAt); err != nil {
+			return nil, err
+		}
+		list = append(list, sess)
+	}
+	return list, rows.Err()
+}
+
+func (s *MySQLSessionService) RevokeSession(id uint64) error {
+	_, err := s.db.Exec("UPDATE sessions SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", time.Now().UTC(), id)
+	return err
+}
+
+func (s *MySQLSessionService) RevokeAllSessions(userID uint64) error {
+	_, err := s.db.Exec("UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL", time.Now().UTC(), userID)
+	return err
+}
+
+func (s *MySQLSessionService) PurgeExpired() (int64, error) {
+	res, err := s.db.Exec("DELETE FROM sessions WHERE expires_at <= ? OR revoked_at IS NOT NULL", time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *MySQLSessionService) ParseSessionCookie(c *gin.Context) *Session {
+	return parseSessionCookie(s, s.cfg.Cookie, c)
+}
+
+func (s *MySQLSessionService) SetSessionCookie(c *gin.Context, token string) {
+	setSessionCookie(s.cfg.Cookie, c, token)
+}
+
+func (s *MySQLSessionService) ClearSessionCookie(c *gin.Context) {
+	clearSessionCookie(s.cfg.Cookie, c)
+}
+
+func (s *MySQLSessionService) CookieValue(token string) string {
+	return s.cfg.Cookie.signCookie(token)
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
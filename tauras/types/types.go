@@ -3,14 +3,14 @@ package types
 import (
 	"database/sql"
 	"tauras/services"
-
-	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"gorm.io/gorm"
 )
 
 type AppContext struct {
 	DB *sql.DB
-	Session *services.SessionService
-	KafkaProducer *kafka.Producer
+	Session services.SessionService
+	Idempotency services.IdempotencyStore
+	Events services.EventPublisher
+	Auctions services.AuctionConfig
 	Gdb *gorm.DB
 }
\ No newline at end of file
@@ -0,0 +1,93 @@
+import asyncio
+import aiohttp
+import websockets
+import json
+import os
+import sys
+
+# Checks that every Pisces replica receives every bid.
+#
+#   docker compose up --scale pisces=3
+#   LOAD_TEST_ADMIN_TOKEN=... python multi_instance.py <auction id> ws://localhost:8081 ws://localhost:8082 ws://localhost:8083
+
+API_BASE = "http://localhost:3000"
+MINT_URL = API_BASE + "/api/admin/loadtest/sessions"
+BID_URL = API_BASE + "/api/auction/bid"
+AUCTION_URL = API_BASE + "/api/auction/{}"
+
+ADMIN_TOKEN = os.environ.get("LOAD_TEST_ADMIN_TOKEN", "")
+BIDS = 10
+TIMEOUT = 10
+
+
+async def collect(url, auction_id, ready, seen):
+    # record the sequence numbers of every bid event this instance delivers
+    async with websockets.connect(f"{url}/ws?auction={auction_id}") as ws:
+        ready.set()
+        while True:
+            data = json.loads(await ws.recv())
+            if data.get("type") == "bid.placed":
+                seen.add(data["seq"])
+
+
+async def place_bids(auction_id):
+    async with aiohttp.ClientSession() as session:
+        headers = {"Authorization": f"Bearer {ADMIN_TOKEN}"}
+        async with session.post(MINT_URL, json={"count": 1}, headers=headers) as resp:
+            if resp.status != 201:
+                sys.exit(f"failed to mint session: {resp.status} {await resp.text()}")
+            cookie = (await resp.json())["sessions"][0]["cookie"]
+
+        async with session.get(AUCTION_URL.format(auction_id)) as resp:
+            price = (await resp.json())["currentPrice"]
+
+        accepted = 0
+        for _ in range(BIDS):
+            price = round(price + 1, 2)
+            async with session.post(
+                BID_URL,
+                json={"Auctionid": auction_id, "Price": price},
+                headers={"Cookie": f"session={cookie}"},
+            ) as resp:
+                if resp.status == 200:
+                    accepted += 1
+        return accepted
+
+
+async def main():
+    if len(sys.argv) < 3:
+        sys.exit("usage: multi_instance.py <auction id> <pisces url>...")
+    if not ADMIN_TOKEN:
+        sys.exit("set LOAD_TEST_ADMIN_TOKEN to the value configured in tauras")
+
+    auction_id = sys.argv[1]
+    urls = sys.argv[2:]
+    seen = {url: set() for url in urls}
+    ready = [asyncio.Event() for _ in urls]
+
+    listeners = [
+        asyncio.create_task(collect(url, auction_id, ready[i], seen[url]))
+        for i, url in enumerate(urls)
+    ]
+    await asyncio.wait_for(asyncio.gather(*(r.wait() for r in ready)), TIMEOUT)
+
+    accepted = await place_bids(auction_id)
+
+    # wait until every instance has seen every accepted bid, or give up
+    loop = asyncio.get_running_loop()
+    deadline = loop.time() + TIMEOUT
+    while loop.time() < deadline and any(len(s) < accepted for s in seen.values()):
+        await asyncio.sleep(0.2)
+    for task in listeners:
+        task.cancel()
+
+    failed = False
+    for url in urls:
+        ok = len(seen[url]) >= accepted
+        failed = failed or not ok
+        print(f"{'OK  ' if ok else 'FAIL'} {url}: {len(seen[url])}/{accepted} bids")
+    sys.exit(1 if failed else 0)
+
+
+if __name__ == "__main__":
+    asyncio.run(main())
@@ -9,8 +9,13 @@ import os
 import sys
 
 AUCTION_ID = input("Input auction id: ")
-HTTP_URL = "http://localhost:3000/api/auction/bid"
-WS_URL = "ws://localhost:8081/ws"
+API_BASE = "http://localhost:3000"
+HTTP_URL = API_BASE + "/api/auction/bid"
+MINT_URL = API_BASE + "/api/admin/loadtest/sessions"
+WS_URL = f"ws://localhost:8081/ws?auction={AUCTION_ID}"
+
+# must match LOAD_TEST_ADMIN_TOKEN in the tauras environment
+ADMIN_TOKEN = os.environ.get("LOAD_TEST_ADMIN_TOKEN", "")
 
 VIEWERS = 100
 BIDDERS = 4
@@ -90,9 +95,10 @@ async def viewer_task(i):
                 try:
                     data = json.loads(message)
 
-                    if "Price" in data:
+                    # snapshots and bid events both carry the current price in their payload
+                    if data.get("type") in ("bid.placed", "auction.snapshot"):
                         async with price_lock:
-                            current_price = float(data["Price"])
+                            current_price = float(data["payload"]["price"])
 
                 except:
                     pass
@@ -101,13 +107,30 @@ async def viewer_task(i):
         pass
 
 
+# ----------------------------
+# SESSIONS
+# ----------------------------
+async def mint_sessions(count):
+    # ask tauras for real short-lived sessions, one per synthetic bidder
+    headers = {"Authorization": f"Bearer {ADMIN_TOKEN}"}
+    async with aiohttp.ClientSession() as session:
+        async with session.post(
+            MINT_URL, json={"count": count, "ttlSeconds": 3600}, headers=headers
+        ) as resp:
+            if resp.status != 201:
+                sys.exit(f"failed to mint sessions: {resp.status} {await resp.text()}")
+            data = await resp.json()
+            return data["sessions"]
+
+
 # ----------------------------
 # BIDDER
 # ----------------------------
-async def bidder_task(i):
+async def bidder_task(i, bidder):
     global total_requests, success_count, fail_count, latencies
 
-    async with aiohttp.ClientSession() as session:
+    headers = {"Cookie": f"session={bidder['cookie']}"}
+    async with aiohttp.ClientSession(headers=headers) as session:
         while True:
             await asyncio.sleep(random.uniform(3, 5))
 
@@ -116,7 +139,6 @@ async def bidder_task(i):
 
             payload = {
                 "auctionid": AUCTION_ID,
-                "userid": "-1",
                 "price": round(bid_price, 2)
             }
 
@@ -147,6 +169,10 @@ async def bidder_task(i):
 async def main():
     tasks = []
 
+    if not ADMIN_TOKEN:
+        sys.exit("set LOAD_TEST_ADMIN_TOKEN to the value configured in tauras")
+    bidders = await mint_sessions(BIDDERS)
+
     # dashboard
     tasks.append(asyncio.create_task(dashboard()))
 
@@ -155,8 +181,8 @@ async def main():
         tasks.append(asyncio.create_task(viewer_task(i)))
 
     # bidders
-    for i in range(1, BIDDERS + 1):
-        tasks.append(asyncio.create_task(bidder_task(i)))
+    for i, bidder in enumerate(bidders, start=1):
+        tasks.append(asyncio.create_task(bidder_task(i, bidder)))
 
     await asyncio.gather(*tasks)
 